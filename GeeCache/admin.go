@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"geecache"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	preloadPrefix  = "/admin/preload"
+	flushPrefix    = "/admin/flush/"
+	maxPreloadJobs = 100 // 最多保留的任务数, 更早的已结束任务会被删除
+)
+
+// preloadRequest 中 Keys 和 File 二选一, File 为预热目录下每行一个 key 的文件
+type preloadRequest struct {
+	Group       string   `json:"group"`
+	Keys        []string `json:"keys"`
+	File        string   `json:"file"`
+	Parallelism int      `json:"parallelism"`
+}
+
+type preloadStatus struct {
+	ID    string `json:"id"`
+	Group string `json:"group"`
+	geecache.PreloadProgress
+}
+
+type preloadJob struct {
+	group string
+	job   *geecache.PreloadJob
+}
+
+var (
+	preloadMu   sync.Mutex
+	preloadSeq  int
+	preloadJobs = make(map[string]*preloadJob)
+)
+
+func (j *preloadJob) status(id string) preloadStatus {
+	return preloadStatus{ID: id, Group: j.group, PreloadProgress: j.job.Progress()}
+}
+
+// pruneJobs 删除最近 maxPreloadJobs 个之前已经结束的任务, 调用时需持有 preloadMu
+func pruneJobs() {
+	for id, job := range preloadJobs {
+		seq, _ := strconv.Atoi(id)
+		if seq <= preloadSeq-maxPreloadJobs && job.job.Progress().Done {
+			delete(preloadJobs, id)
+		}
+	}
+}
+
+// preloadPath 返回 file 在 dir 下的路径, 不允许访问 dir 之外的文件, dir 为空时不允许从文件预热
+func preloadPath(dir string, file string) (string, error) {
+	if dir == "" {
+		return "", errors.New("preload from file is disabled")
+	}
+	dir = filepath.Clean(dir)
+	path := filepath.Join(dir, filepath.Clean("/"+file))
+	if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid preload file %q", file)
+	}
+	return path, nil
+}
+
+// readKeys 读取每行一个 key 的文件, 忽略空行
+func readKeys(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var keys []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if key := strings.TrimSpace(scanner.Text()); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys, scanner.Err()
+}
+
+// preloadHandler 管理预热任务, file 只能是 dir 下的文件:
+//
+//	POST   /admin/preload       创建任务, 请求体为 {"group": ..., "keys": [...]} 或 {"group": ..., "file": ...}
+//	GET    /admin/preload       列出所有任务
+//	GET    /admin/preload/<id>  查询进度
+//	DELETE /admin/preload/<id>  取消任务
+func preloadHandler(dir string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, preloadPrefix), "/")
+		switch {
+		case id == "" && r.Method == http.MethodPost:
+			startPreload(w, r, dir)
+		case id == "" && r.Method == http.MethodGet:
+			preloadMu.Lock()
+			statuses := make([]preloadStatus, 0, len(preloadJobs))
+			for id, job := range preloadJobs {
+				statuses = append(statuses, job.status(id))
+			}
+			preloadMu.Unlock()
+			writeJSON(w, http.StatusOK, statuses)
+		case id != "" && (r.Method == http.MethodGet || r.Method == http.MethodDelete):
+			preloadMu.Lock()
+			job, ok := preloadJobs[id]
+			preloadMu.Unlock()
+			if !ok {
+				writeError(w, http.StatusNotFound, "no such preload job: "+id)
+				return
+			}
+			if r.Method == http.MethodDelete {
+				job.job.Cancel()
+			}
+			writeJSON(w, http.StatusOK, job.status(id))
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed: "+r.Method)
+		}
+	})
+}
+
+func startPreload(w http.ResponseWriter, r *http.Request, dir string) {
+	req := preloadRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid preload request: "+err.Error())
+		return
+	}
+	gee := geecache.GetGroup(req.Group)
+	if gee == nil {
+		writeError(w, http.StatusNotFound, "no such group: "+req.Group)
+		return
+	}
+	if (len(req.Keys) == 0) == (req.File == "") {
+		writeError(w, http.StatusBadRequest, "exactly one of keys and file is required")
+		return
+	}
+
+	keys := req.Keys
+	if req.File != "" {
+		path, err := preloadPath(dir, req.File)
+		if err != nil {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if keys, err = readKeys(path); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	// 任务不随请求结束而取消
+	job := &preloadJob{
+		group: req.Group,
+		job:   gee.PreloadContext(context.Background(), keys, req.Parallelism),
+	}
+	preloadMu.Lock()
+	preloadSeq++
+	id := strconv.Itoa(preloadSeq)
+	preloadJobs[id] = job
+	pruneJobs()
+	preloadMu.Unlock()
+
+	w.Header().Set("Location", preloadPrefix+"/"+id)
+	writeJSON(w, http.StatusAccepted, job.status(id))
+}
+
+type flushResponse struct {
+	Group      string `json:"group"`
+	Generation uint64 `json:"generation"`
+	Error      string `json:"error,omitempty"`
+}
+
+// flushHandler 处理 POST /admin/flush/<group>, 使整个集群中该 Group 的缓存失效
+// 部分节点通知失败时返回 502, 这些节点会在收到其他节点的请求后追上
+func flushHandler(peers *geecache.HTTPPool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed: "+r.Method)
+			return
+		}
+		group := strings.TrimPrefix(r.URL.Path, flushPrefix)
+		if geecache.GetGroup(group) == nil {
+			writeError(w, http.StatusNotFound, "no such group: "+group)
+			return
+		}
+
+		generation, err := peers.Flush(r.Context(), group)
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, flushResponse{Group: group, Generation: generation, Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, flushResponse{Group: group, Generation: generation})
+	})
+}
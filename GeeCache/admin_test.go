@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"geecache"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+// newTestGroup 创建以 key 作为 value 的 Group, 测试结束时删除
+func newTestGroup(t *testing.T, name string) *geecache.Group {
+	g, err := geecache.NewGroup(name, 2<<10, geecache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { geecache.DestroyGroup(name) })
+	return g
+}
+
+func do(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return w
+}
+
+func TestPreloadHandler(t *testing.T) {
+	newTestGroup(t, "admin-preload")
+	root, err := ioutil.TempDir("", "preload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "keys")
+	secret := filepath.Join(root, "secret.txt")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "keys.txt"), []byte("Tom\n\nJack\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(secret, []byte("Tom\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	h := preloadHandler(dir)
+
+	c.Convey("按 key 或目录下的文件预热", t, func() {
+		w := do(h, http.MethodPost, preloadPrefix, `{"group": "admin-preload", "keys": ["Tom", "Sam"]}`)
+		c.So(w.Code, c.ShouldEqual, http.StatusAccepted)
+		status := preloadStatus{}
+		c.So(json.Unmarshal(w.Body.Bytes(), &status), c.ShouldBeNil)
+		c.So(w.Header().Get("Location"), c.ShouldEqual, preloadPrefix+"/"+status.ID)
+
+		for !status.Done {
+			time.Sleep(time.Millisecond)
+			w = do(h, http.MethodGet, preloadPrefix+"/"+status.ID, "")
+			c.So(json.Unmarshal(w.Body.Bytes(), &status), c.ShouldBeNil)
+		}
+		c.So(status.Loaded, c.ShouldEqual, 2)
+
+		w = do(h, http.MethodPost, preloadPrefix, `{"group": "admin-preload", "file": "keys.txt"}`)
+		c.So(w.Code, c.ShouldEqual, http.StatusAccepted)
+		c.So(json.Unmarshal(w.Body.Bytes(), &status), c.ShouldBeNil)
+		c.So(status.Total, c.ShouldEqual, 2)
+	})
+
+	c.Convey("不允许读取目录之外的文件", t, func() {
+		// 路径被限制在目录之内, 目录外的文件在目录中不存在
+		for _, file := range []string{"../secret.txt", secret, "a/../../secret.txt"} {
+			w := do(h, http.MethodPost, preloadPrefix, `{"group": "admin-preload", "file": "`+file+`"}`)
+			c.So(w.Code, c.ShouldEqual, http.StatusBadRequest)
+		}
+		w := do(h, http.MethodPost, preloadPrefix, `{"group": "admin-preload", "file": ".."}`)
+		c.So(w.Code, c.ShouldEqual, http.StatusForbidden)
+		w = do(preloadHandler(""), http.MethodPost, preloadPrefix, `{"group": "admin-preload", "file": "keys.txt"}`)
+		c.So(w.Code, c.ShouldEqual, http.StatusForbidden)
+	})
+
+	c.Convey("错误的请求", t, func() {
+		c.So(do(h, http.MethodPost, preloadPrefix, `{`).Code, c.ShouldEqual, http.StatusBadRequest)
+		c.So(do(h, http.MethodPost, preloadPrefix, `{"group": "nobody", "keys": ["Tom"]}`).Code, c.ShouldEqual, http.StatusNotFound)
+		c.So(do(h, http.MethodPost, preloadPrefix, `{"group": "admin-preload"}`).Code, c.ShouldEqual, http.StatusBadRequest)
+		c.So(do(h, http.MethodGet, preloadPrefix+"/0", "").Code, c.ShouldEqual, http.StatusNotFound)
+		c.So(do(h, http.MethodPut, preloadPrefix, "").Code, c.ShouldEqual, http.StatusMethodNotAllowed)
+	})
+}
+
+func TestPreloadPrune(t *testing.T) {
+	newTestGroup(t, "admin-prune")
+	h := preloadHandler("")
+
+	c.Convey("只保留最近的任务", t, func() {
+		var last string
+		for i := 0; i < maxPreloadJobs*2; i++ {
+			w := do(h, http.MethodPost, preloadPrefix, `{"group": "admin-prune", "keys": ["Tom"]}`)
+			last = strings.TrimPrefix(w.Header().Get("Location"), preloadPrefix+"/")
+			// 等待任务结束, 只有结束的任务会被删除
+			for w := do(h, http.MethodGet, preloadPrefix+"/"+last, ""); !strings.Contains(w.Body.String(), `"done":true`); w = do(h, http.MethodGet, preloadPrefix+"/"+last, "") {
+				time.Sleep(time.Millisecond)
+			}
+		}
+		do(h, http.MethodPost, preloadPrefix, `{"group": "admin-prune", "keys": ["Tom"]}`)
+
+		preloadMu.Lock()
+		defer preloadMu.Unlock()
+		c.So(len(preloadJobs), c.ShouldBeLessThanOrEqualTo, maxPreloadJobs)
+		c.So(preloadJobs, c.ShouldContainKey, last)
+		seq, _ := strconv.Atoi(last)
+		c.So(preloadJobs, c.ShouldNotContainKey, strconv.Itoa(seq-maxPreloadJobs))
+	})
+}
+
+func TestFlushHandler(t *testing.T) {
+	g := newTestGroup(t, "admin-flush")
+	peers := geecache.NewHTTPPool("http://localhost:0")
+	peers.Set("http://localhost:0")
+	g.RegisterPeers(peers)
+	h := flushHandler(peers)
+
+	c.Convey("Flush 使 Group 的缓存失效", t, func() {
+		w := do(h, http.MethodPost, flushPrefix+"admin-flush", "")
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		res := flushResponse{}
+		c.So(json.Unmarshal(w.Body.Bytes(), &res), c.ShouldBeNil)
+		c.So(res, c.ShouldResemble, flushResponse{Group: "admin-flush", Generation: g.Generation()})
+		c.So(res.Generation, c.ShouldEqual, 1)
+
+		c.So(do(h, http.MethodPost, flushPrefix+"nobody", "").Code, c.ShouldEqual, http.StatusNotFound)
+		w = do(h, http.MethodGet, flushPrefix+"admin-flush", "")
+		c.So(w.Code, c.ShouldEqual, http.StatusMethodNotAllowed)
+		c.So(w.Header().Get("Allow"), c.ShouldEqual, "POST")
+	})
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"geecache"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	apiPrefix    = "/api/"
+	batchKey     = "_batch" // POST /api/<group>/_batch
+	maxBatchKeys = 1000
+	maxBodyBytes = 32 << 20
+)
+
+// apiError 是所有错误响应的 JSON 格式
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type batchRequest struct {
+	Keys []string `json:"keys"`
+}
+
+// batchResult 中的 Value 会被编码为 base64
+type batchResult struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value,omitempty"`
+	ETag  string `json:"etag,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Results []batchResult `json:"results"`
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, apiError{Error: msg})
+}
+
+// statusOf 将 Group 返回的错误映射为 HTTP 状态码
+func statusOf(err error) int {
+	if errors.Is(err, geecache.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, geecache.ErrValueTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
+
+// etagOf 根据 value 的哈希生成强 ETag
+func etagOf(view geecache.ByteView) string {
+	h := sha1.New()
+	io.Copy(h, view.Reader())
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
+
+// etagMatch 判断 If-None-Match 中是否包含 etag
+func etagMatch(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// apiHandler 提供 REST 接口:
+//
+//	GET|HEAD /api/<group>/<key>  读取, 支持 If-None-Match
+//	PUT      /api/<group>/<key>  写入负责 key 的节点
+//	DELETE   /api/<group>/<key>  使负责 key 的节点和本节点的缓存失效
+//	POST     /api/<group>/_batch 批量读取, 请求体为 {"keys": [...]}
+func apiHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, apiPrefix), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		writeError(w, http.StatusBadRequest, "expected /api/<group>/<key>")
+		return
+	}
+	groupName, key := parts[0], parts[1]
+
+	gee := geecache.GetGroup(groupName)
+	if gee == nil {
+		writeError(w, http.StatusNotFound, "no such group: "+groupName)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		getKey(w, r, gee, key)
+	case r.Method == http.MethodPut:
+		putKey(w, r, gee, key)
+	case r.Method == http.MethodDelete:
+		if err := gee.Delete(r.Context(), key); err != nil {
+			writeError(w, statusOf(err), err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && key == batchKey:
+		getBatch(w, r, gee)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, DELETE, POST")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed: "+r.Method)
+	}
+}
+
+func getKey(w http.ResponseWriter, r *http.Request, gee *geecache.Group, key string) {
+	view, err := gee.Get(key)
+	if err != nil {
+		writeError(w, statusOf(err), err.Error())
+		return
+	}
+
+	etag := etagOf(view)
+	w.Header().Set("ETag", etag)
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatch(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(view.Len()))
+	if r.Method == http.MethodHead {
+		return
+	}
+	io.Copy(w, view.Reader())
+}
+
+func putKey(w http.ResponseWriter, r *http.Request, gee *geecache.Group, key string) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	if err := gee.Put(r.Context(), key, body); err != nil {
+		writeError(w, statusOf(err), err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func getBatch(w http.ResponseWriter, r *http.Request, gee *geecache.Group) {
+	req := batchRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid batch request: "+err.Error())
+		return
+	}
+	if len(req.Keys) > maxBatchKeys {
+		writeError(w, http.StatusRequestEntityTooLarge, "too many keys, max "+strconv.Itoa(maxBatchKeys))
+		return
+	}
+
+	res := batchResponse{Results: make([]batchResult, 0, len(req.Keys))}
+	for _, key := range req.Keys {
+		view, err := gee.Get(key)
+		var value []byte
+		if err == nil {
+			value, err = view.Bytes()
+		}
+		if err != nil {
+			res.Results = append(res.Results, batchResult{Key: key, Error: err.Error()})
+			continue
+		}
+		res.Results = append(res.Results, batchResult{Key: key, Value: value, ETag: etagOf(view)})
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&ready) == 0 {
+		writeError(w, http.StatusServiceUnavailable, "not ready")
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
+// newAPIServer 创建 API 服务, preloadDir 是允许从文件预热的目录, 为空时只能按 key 预热
+func newAPIServer(apiAddr string, preloadDir string, peers *geecache.HTTPPool) (*http.Server, error) {
+	host, err := hostOf(apiAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(apiPrefix, http.HandlerFunc(apiHandler))
+	mux.Handle("/readyz", http.HandlerFunc(readyHandler))
+	mux.Handle(preloadPrefix, preloadHandler(preloadDir))
+	mux.Handle(preloadPrefix+"/", preloadHandler(preloadDir))
+	mux.Handle(flushPrefix, flushHandler(peers))
+	return &http.Server{Addr: host, Handler: mux}, nil
+}
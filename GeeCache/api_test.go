@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAPIGet(t *testing.T) {
+	g := newTestGroup(t, "api-get")
+	g.SetMaxValueSize(8)
+	h := http.HandlerFunc(apiHandler)
+
+	c.Convey("读取并支持 ETag", t, func() {
+		w := do(h, http.MethodGet, "/api/api-get/Tom", "")
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		c.So(w.Body.String(), c.ShouldEqual, "Tom")
+		etag := w.Header().Get("ETag")
+		c.So(etag, c.ShouldStartWith, `"`)
+
+		r := httptest.NewRequest(http.MethodGet, "/api/api-get/Tom", nil)
+		r.Header.Set("If-None-Match", `"other", W/`+etag)
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		c.So(w.Code, c.ShouldEqual, http.StatusNotModified)
+		c.So(w.Body.Len(), c.ShouldEqual, 0)
+
+		w = do(h, http.MethodHead, "/api/api-get/Tom", "")
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		c.So(w.Header().Get("Content-Length"), c.ShouldEqual, "3")
+		c.So(w.Header().Get("ETag"), c.ShouldEqual, etag)
+		c.So(w.Body.Len(), c.ShouldEqual, 0)
+	})
+
+	c.Convey("错误以 JSON 返回", t, func() {
+		for path, code := range map[string]int{
+			"/api/api-get":           http.StatusBadRequest,
+			"/api/nobody/Tom":        http.StatusNotFound,
+			"/api/api-get/123456789": http.StatusRequestEntityTooLarge,
+		} {
+			w := do(h, http.MethodGet, path, "")
+			c.So(w.Code, c.ShouldEqual, code)
+			c.So(w.Header().Get("Content-Type"), c.ShouldEqual, "application/json")
+			res := apiError{}
+			c.So(json.Unmarshal(w.Body.Bytes(), &res), c.ShouldBeNil)
+			c.So(res.Error, c.ShouldNotBeEmpty)
+		}
+
+		w := do(h, http.MethodPatch, "/api/api-get/Tom", "")
+		c.So(w.Code, c.ShouldEqual, http.StatusMethodNotAllowed)
+		c.So(w.Header().Get("Allow"), c.ShouldEqual, "GET, HEAD, PUT, DELETE, POST")
+	})
+}
+
+func TestAPIPutDelete(t *testing.T) {
+	g := newTestGroup(t, "api-put")
+	g.SetMaxValueSize(8)
+	h := http.HandlerFunc(apiHandler)
+
+	c.Convey("写入和失效", t, func() {
+		c.So(do(h, http.MethodPut, "/api/api-put/Tom", "630").Code, c.ShouldEqual, http.StatusNoContent)
+		c.So(do(h, http.MethodGet, "/api/api-put/Tom", "").Body.String(), c.ShouldEqual, "630")
+		c.So(do(h, http.MethodPut, "/api/api-put/Tom", "123456789").Code, c.ShouldEqual, http.StatusRequestEntityTooLarge)
+
+		c.So(do(h, http.MethodDelete, "/api/api-put/Tom", "").Code, c.ShouldEqual, http.StatusNoContent)
+		c.So(do(h, http.MethodGet, "/api/api-put/Tom", "").Body.String(), c.ShouldEqual, "Tom")
+	})
+}
+
+func TestAPIBatch(t *testing.T) {
+	g := newTestGroup(t, "api-batch")
+	g.SetMaxValueSize(8)
+	h := http.HandlerFunc(apiHandler)
+
+	c.Convey("批量读取", t, func() {
+		w := do(h, http.MethodPost, "/api/api-batch/_batch", `{"keys": ["Tom", "123456789"]}`)
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		res := batchResponse{}
+		c.So(json.Unmarshal(w.Body.Bytes(), &res), c.ShouldBeNil)
+		c.So(len(res.Results), c.ShouldEqual, 2)
+		c.So(string(res.Results[0].Value), c.ShouldEqual, "Tom")
+		c.So(res.Results[0].ETag, c.ShouldNotBeEmpty)
+		c.So(res.Results[1].Key, c.ShouldEqual, "123456789")
+		c.So(res.Results[1].Error, c.ShouldNotBeEmpty)
+
+		c.So(do(h, http.MethodPost, "/api/api-batch/_batch", `{`).Code, c.ShouldEqual, http.StatusBadRequest)
+		keys := make([]string, maxBatchKeys+1)
+		for i := range keys {
+			keys[i] = strconv.Quote(strconv.Itoa(i))
+		}
+		body := `{"keys": [` + strings.Join(keys, ",") + `]}`
+		c.So(do(h, http.MethodPost, "/api/api-batch/_batch", body).Code, c.ShouldEqual, http.StatusRequestEntityTooLarge)
+	})
+}
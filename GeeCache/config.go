@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"geecache"
+	"io/ioutil"
+	"net/url"
+)
+
+// Config 描述一个 GeeCache 集群
+type Config struct {
+	API    string        `json:"api"`    // 前端 API 服务地址, 如 http://localhost:9999, 启用 API 服务时必填
+	Peers  []string      `json:"peers"`  // 所有缓存节点的地址
+	Groups []GroupConfig `json:"groups"` // 每个节点上都会创建的 Group
+	Limit  LimitConfig   `json:"limit"`  // 可选, 节点间请求的并发限制
+
+	PreloadDir string `json:"preload_dir"` // 可选, 预热接口只能读取该目录下的 key 文件
+}
+
+// LimitConfig 限制每个节点同时处理的节点间请求, MaxInflight 为 0 时不限制
+type LimitConfig struct {
+	MaxInflight    int `json:"max_inflight"`
+	MaxQueue       int `json:"max_queue"`
+	QueueTimeoutMS int `json:"queue_timeout_ms"`
+}
+
+type GroupConfig struct {
+	Name              string        `json:"name"`
+	CacheBytes        int64         `json:"cache_bytes"`
+	ChunkSize         int           `json:"chunk_size"`         // 可选, 超过该大小的值分块存储和传输
+	MaxValueSize      int64         `json:"max_value_size"`     // 可选, 允许缓存的最大值
+	Compression       string        `json:"compression"`        // 可选, gzip 或 flate
+	CompressThreshold int           `json:"compress_threshold"` // 可选, 不小于该大小的值才压缩
+	Tenants           *TenantConfig `json:"tenants"`            // 可选, 按 key 的前缀划分租户
+	Source            SourceConfig  `json:"source"`
+}
+
+// TenantConfig 以 key 中 Separator 之前的部分作为租户, Quotas 为部分租户指定字节配额
+type TenantConfig struct {
+	Separator string           `json:"separator"` // 默认为 ":"
+	Quotas    map[string]int64 `json:"quotas"`
+}
+
+// SourceConfig 描述缓存未命中时的数据源
+type SourceConfig struct {
+	Type string `json:"type"` // json: 静态 JSON 文件; dir: 目录, 文件名即 key; http: HTTP 服务
+	Path string `json:"path"` // json 和 dir 使用
+	URL  string `json:"url"`  // http 使用, 请求 <url>/<key>
+}
+
+// loadConfig 读取并检查配置, api 为 true 时要求配置 API 服务地址
+func loadConfig(path string, api bool) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := &Config{}
+	if err := json.Unmarshal(data, conf); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", path, err)
+	}
+	if err := conf.validate(api); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	return conf, nil
+}
+
+func (c *Config) validate(api bool) error {
+	if len(c.Peers) == 0 {
+		return fmt.Errorf("no peers")
+	}
+	for _, peer := range c.Peers {
+		if _, err := hostOf(peer); err != nil {
+			return err
+		}
+	}
+	if api {
+		if _, err := hostOf(c.API); err != nil {
+			return fmt.Errorf("api: %v", err)
+		}
+	}
+	if len(c.Groups) == 0 {
+		return fmt.Errorf("no groups")
+	}
+	names := make(map[string]bool, len(c.Groups))
+	for _, g := range c.Groups {
+		if g.Name == "" {
+			return fmt.Errorf("group without name")
+		}
+		if names[g.Name] {
+			return fmt.Errorf("duplicate group %s", g.Name)
+		}
+		names[g.Name] = true
+		if g.CacheBytes <= 0 {
+			return fmt.Errorf("group %s: cache_bytes must be positive", g.Name)
+		}
+		if _, err := codecOf(g.Compression); err != nil {
+			return fmt.Errorf("group %s: %v", g.Name, err)
+		}
+	}
+	return nil
+}
+
+// codecOf 返回配置中的压缩编码, 为空时不压缩
+func codecOf(name string) (geecache.Codec, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "gzip":
+		return geecache.Gzip, nil
+	case "flate":
+		return geecache.Flate, nil
+	default:
+		return nil, fmt.Errorf("unknown compression %q", name)
+	}
+}
+
+// hasPeer 判断 addr 是否在节点列表中
+func (c *Config) hasPeer(addr string) bool {
+	for _, peer := range c.Peers {
+		if peer == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// hostOf 从 http://host:port 中取出 host:port 用于监听
+func hostOf(addr string) (string, error) {
+	u, err := url.Parse(addr)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid address %q", addr)
+	}
+	return u.Host, nil
+}
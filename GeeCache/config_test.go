@@ -0,0 +1,96 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func validConfig() *Config {
+	return &Config{
+		API:   "http://localhost:9999",
+		Peers: []string{"http://localhost:8001", "http://localhost:8002"},
+		Groups: []GroupConfig{
+			{Name: "scores", CacheBytes: 2048, Source: SourceConfig{Type: "json", Path: "data/scores.json"}},
+		},
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	c.Convey("检查配置", t, func() {
+		c.So(validConfig().validate(true), c.ShouldBeNil)
+
+		conf := validConfig()
+		conf.API = ""
+		c.So(conf.validate(false), c.ShouldBeNil)
+		c.So(conf.validate(true), c.ShouldNotBeNil)
+
+		for _, broken := range []func(conf *Config){
+			func(conf *Config) { conf.Peers = nil },
+			func(conf *Config) { conf.Peers[1] = "localhost:8002" },
+			func(conf *Config) { conf.Groups = nil },
+			func(conf *Config) { conf.Groups[0].Name = "" },
+			func(conf *Config) { conf.Groups = append(conf.Groups, conf.Groups[0]) },
+			func(conf *Config) { conf.Groups[0].CacheBytes = 0 },
+			func(conf *Config) { conf.Groups[0].Compression = "zip" },
+		} {
+			conf := validConfig()
+			broken(conf)
+			c.So(conf.validate(false), c.ShouldNotBeNil)
+		}
+	})
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	c.Convey("读取配置文件", t, func() {
+		conf, err := loadConfig("cluster.json", true)
+		c.So(err, c.ShouldBeNil)
+		c.So(conf.hasPeer("http://localhost:8001"), c.ShouldBeTrue)
+		c.So(conf.hasPeer("http://localhost:9999"), c.ShouldBeFalse)
+
+		_, err = loadConfig(write("invalid.json", "{"), false)
+		c.So(err, c.ShouldNotBeNil)
+		_, err = loadConfig(write("empty.json", "{}"), false)
+		c.So(err, c.ShouldNotBeNil)
+		_, err = loadConfig(filepath.Join(dir, "missing.json"), false)
+		c.So(err, c.ShouldNotBeNil)
+	})
+}
+
+func TestHostOf(t *testing.T) {
+	c.Convey("从地址中取出 host:port", t, func() {
+		host, err := hostOf("http://localhost:8001")
+		c.So(err, c.ShouldBeNil)
+		c.So(host, c.ShouldEqual, "localhost:8001")
+		_, err = hostOf("localhost:8001")
+		c.So(err, c.ShouldNotBeNil)
+		_, err = hostOf("")
+		c.So(err, c.ShouldNotBeNil)
+	})
+}
+
+func TestNewCacheServerLimit(t *testing.T) {
+	peers := []string{"http://localhost:8001"}
+	c.Convey("并发限制为 0 时不限制, 为负数时返回错误", t, func() {
+		_, _, err := newCacheServer(peers[0], peers, LimitConfig{}, nil)
+		c.So(err, c.ShouldBeNil)
+		_, _, err = newCacheServer(peers[0], peers, LimitConfig{MaxInflight: 4, MaxQueue: -1}, nil)
+		c.So(err, c.ShouldNotBeNil)
+	})
+}
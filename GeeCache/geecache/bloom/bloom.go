@@ -0,0 +1,66 @@
+// Package bloom 实现布隆过滤器, 用来快速判断一个 key 一定不存在
+package bloom
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// Filter 是布隆过滤器, 构建完成后只读, 可以并发调用 Has
+type Filter struct {
+	bits []uint64
+	m    uint64 // 位数
+	k    uint64 // 哈希函数个数
+}
+
+// New 创建能容纳 n 个 key, 误判率约为 p 的过滤器
+func New(n int, p float64) *Filter {
+	if n < 1 {
+		n = 1
+	}
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &Filter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// hashes 使用双重哈希, 第 i 个下标为 h1 + i*h2
+func hashes(key string) (h1, h2 uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	return sum & 0xffffffff, sum>>32 | 1
+}
+
+// Add 加入一个 key, 不是并发安全的
+func (f *Filter) Add(key string) {
+	h1, h2 := hashes(key)
+	for i := uint64(0); i < f.k; i++ {
+		idx := (h1 + i*h2) % f.m
+		f.bits[idx/64] |= 1 << (idx % 64)
+	}
+}
+
+// Has 返回 false 时 key 一定不存在, 返回 true 时 key 可能存在
+func (f *Filter) Has(key string) bool {
+	h1, h2 := hashes(key)
+	for i := uint64(0); i < f.k; i++ {
+		idx := (h1 + i*h2) % f.m
+		if f.bits[idx/64]&(1<<(idx%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
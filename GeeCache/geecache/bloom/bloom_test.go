@@ -0,0 +1,29 @@
+package bloom
+
+import (
+	"strconv"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFilter(t *testing.T) {
+	f := New(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		f.Add("key" + strconv.Itoa(i))
+	}
+
+	c.Convey("已加入的 key 一定存在, 误判率接近设定值", t, func() {
+		for i := 0; i < 1000; i++ {
+			c.So(f.Has("key"+strconv.Itoa(i)), c.ShouldBeTrue)
+		}
+		falsePositives := 0
+		for i := 0; i < 10000; i++ {
+			if f.Has("missing" + strconv.Itoa(i)) {
+				falsePositives++
+			}
+		}
+		c.So(falsePositives, c.ShouldBeLessThan, 200)
+		c.So(f.k, c.ShouldEqual, 7)
+	})
+}
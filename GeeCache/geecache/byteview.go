@@ -1,16 +1,87 @@
 package geecache
 
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"sync/atomic"
+)
+
+// decodeErrors 统计 ByteSlice 解压失败的次数
+var decodeErrors int64
+
 type ByteView struct {
-	b []byte // 存储真实的缓存值
+	b       []byte   // 存储真实的缓存值
+	chunks  [][]byte // 大于分块阈值的值按块存储, 此时 b 为空
+	version uint64   // 写入负责节点缓存时分配的版本号, 0 表示没有版本
+	codec   Codec    // 不为 nil 时 b 和 chunks 中是压缩后的数据
+	n       int      // 压缩前的长度
+
+	generation uint64 // 写入时 Group 的代数
+}
+
+// newByteView 拷贝 b, chunkSize 大于 0 且 b 更长时分块存储, 避免一次申请大块连续内存
+func newByteView(b []byte, chunkSize int) ByteView {
+	if chunkSize <= 0 || len(b) <= chunkSize {
+		return ByteView{b: cloneBytes(b)}
+	}
+	chunks := make([][]byte, 0, (len(b)+chunkSize-1)/chunkSize)
+	for len(b) > 0 {
+		n := chunkSize
+		if n > len(b) {
+			n = len(b)
+		}
+		chunks = append(chunks, cloneBytes(b[:n]))
+		b = b[n:]
+	}
+	return ByteView{chunks: chunks}
 }
 
 func (v ByteView) Len() int {
-	return len(v.b)
+	if v.codec != nil {
+		return v.n
+	}
+	return v.size()
 }
 
+// size 返回实际占用的字节数, 压缩的值为压缩后的大小
+func (v ByteView) size() int {
+	if v.chunks == nil {
+		return len(v.b)
+	}
+	n := 0
+	for _, c := range v.chunks {
+		n += len(c)
+	}
+	return n
+}
+
+// ByteSlice 返回值的拷贝, 解压失败时记录日志并计入 Stats.DecodeErrors, 返回 nil
+// 需要区分错误时使用 Bytes
 func (v ByteView) ByteSlice() []byte {
-	// 返回的是拷贝, 防止只读数据被修改
-	return v.cloneBytes(v.b)
+	b, err := v.Bytes()
+	if err != nil {
+		atomic.AddInt64(&decodeErrors, 1)
+		log.Println("[GeeCache] Failed to decode value.", err)
+		return nil
+	}
+	return b
+}
+
+// Bytes 返回值的拷贝, 防止只读数据被修改, 压缩的值解压失败时返回错误
+func (v ByteView) Bytes() ([]byte, error) {
+	if v.codec != nil {
+		return ioutil.ReadAll(v.Reader())
+	}
+	if v.chunks == nil {
+		return v.cloneBytes(v.b), nil
+	}
+	b := make([]byte, 0, v.Len())
+	for _, c := range v.chunks {
+		b = append(b, c...)
+	}
+	return b, nil
 }
 
 func (v ByteView) cloneBytes(b []byte) []byte {
@@ -18,7 +89,43 @@ func (v ByteView) cloneBytes(b []byte) []byte {
 }
 
 func (v ByteView) String() string {
-	return string(v.b)
+	return string(v.ByteSlice())
+}
+
+// Version 返回值的版本号, 用于 CompareAndSet
+func (v ByteView) Version() uint64 {
+	return v.version
+}
+
+// Reader 返回按顺序读取整个值的 io.Reader, 不会拷贝数据, 压缩的值边读边解压
+// 解压后的长度必须等于压缩前的长度, 远程节点声明的大小已经检查过, 解压时不会读入更多数据
+func (v ByteView) Reader() io.Reader {
+	r := v.rawReader()
+	if v.codec == nil {
+		return r
+	}
+	dr, err := v.codec.NewReader(r)
+	if err != nil {
+		return errReader{err}
+	}
+	return newSizedReader(dr, int64(v.n))
+}
+
+// rawReader 读取实际存储的数据
+func (v ByteView) rawReader() io.Reader {
+	if v.chunks == nil {
+		return bytes.NewReader(v.b)
+	}
+	readers := make([]io.Reader, len(v.chunks))
+	for i, c := range v.chunks {
+		readers[i] = bytes.NewReader(c)
+	}
+	return io.MultiReader(readers...)
+}
+
+// chunked 判断是否分块存储
+func (v ByteView) chunked() bool {
+	return v.chunks != nil
 }
 
 func cloneBytes(b []byte) []byte {
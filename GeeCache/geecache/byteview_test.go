@@ -0,0 +1,57 @@
+package geecache
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func TestByteViewChunks(t *testing.T) {
+	c.Convey("分块存储测试", t, func() {
+		tt := []struct {
+			name      string
+			value     string
+			chunkSize int
+			chunks    int
+		}{
+			{name: "不分块", value: "0123456789", chunkSize: 0, chunks: 0},
+			{name: "未超过阈值", value: "0123456789", chunkSize: 10, chunks: 0},
+			{name: "整除", value: "0123456789", chunkSize: 5, chunks: 2},
+			{name: "最后一块较短", value: "0123456789", chunkSize: 4, chunks: 3},
+		}
+		for _, tc := range tt {
+			c.Convey(tc.name, func() {
+				v := newByteView([]byte(tc.value), tc.chunkSize)
+				c.So(len(v.chunks), c.ShouldEqual, tc.chunks)
+				c.So(v.Len(), c.ShouldEqual, len(tc.value))
+				c.So(v.String(), c.ShouldEqual, tc.value)
+				b, _ := ioutil.ReadAll(v.Reader())
+				c.So(string(b), c.ShouldEqual, tc.value)
+			})
+		}
+	})
+}
+
+func TestByteViewDecodeError(t *testing.T) {
+	c.Convey("解压失败时返回错误", t, func() {
+		v := ByteView{b: []byte("not gzip"), codec: Gzip, n: 8}
+		_, err := v.Bytes()
+		c.So(err, c.ShouldNotBeNil)
+
+		// 解压后的长度与声明的大小不一致
+		raw := bytes.Repeat([]byte("x"), 100)
+		compressed, _ := Gzip.Encode(raw)
+		for n, ok := range map[int]bool{10: false, 100: true, 200: false} {
+			b, err := ByteView{b: compressed, codec: Gzip, n: n}.Bytes()
+			c.So(err == nil, c.ShouldEqual, ok)
+			c.So(len(b) <= n, c.ShouldBeTrue)
+		}
+
+		before := atomic.LoadInt64(&decodeErrors)
+		c.So(v.ByteSlice(), c.ShouldBeNil)
+		c.So(atomic.LoadInt64(&decodeErrors), c.ShouldEqual, before+1)
+	})
+}
@@ -5,33 +5,184 @@ import (
 	"sync"
 )
 
+// AdmissionPolicy 决定新的 key 是否可以挤出 LRU 中最久未使用的 key
+// 调用时已持有 cache 的锁, 实现无需再考虑并发
+type AdmissionPolicy interface {
+	Record(key string)                   // 记录一次访问
+	Admit(candidate, victim string) bool // candidate 是否可以替换 victim
+}
+
+// storedView 是 LRU 中保存的值, 按实际占用的字节数 (压缩后的大小) 计算缓存大小
+type storedView struct {
+	view ByteView
+}
+
+func (v storedView) Len() int {
+	return v.view.size()
+}
+
 type cache struct {
-	mu         sync.Mutex // 互斥锁
-	lru        *lru.Cache // LRU 缓存
-	cacheBytes int64      // 缓存大小
+	mu         sync.Mutex                       // 互斥锁
+	lru        *lru.Cache                       // LRU 缓存
+	cacheBytes int64                            // 缓存大小
+	onEvicted  func(key string, value ByteView) // 条目被淘汰时的回调函数
+	admission  AdmissionPolicy                  // 准入策略, 为 nil 时全部接纳
+	tenants    *tenants                         // 不为 nil 时每个租户使用独立的 LRU
 }
 
-func (c *cache) add(key string, value ByteView) {
+// add 写入 key, 返回是否被准入策略接纳
+func (c *cache) add(key string, value ByteView) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.lru == nil {
+	l := c.lruFor(key, true)
+	if !c.admit(l, key, value) {
+		return false
+	}
+	l.Add(key, storedView{value})
+	return true
+}
+
+// lruFor 返回 key 所在的 LRU, create 为 false 且 LRU 还不存在时返回 nil
+func (c *cache) lruFor(key string, create bool) *lru.Cache {
+	if c.tenants != nil {
+		return c.tenants.lruFor(key, create, c.evicted)
+	}
+	if c.lru == nil && create {
 		// Lazy Initialization
 		// 提高性能并减少内存需求
-		c.lru = lru.New(c.cacheBytes, nil)
+		c.lru = lru.New(c.cacheBytes, c.evicted)
+	}
+	return c.lru
+}
+
+// admit 只有在写入会触发淘汰时才询问准入策略
+func (c *cache) admit(l *lru.Cache, key string, value ByteView) bool {
+	if c.admission == nil || l.MaxBytes() == 0 {
+		return true
+	}
+	if _, ok := l.Peek(key); ok {
+		return true
+	}
+	if l.Bytes()+int64(len(key))+int64(value.size()) <= l.MaxBytes() {
+		return true
+	}
+	victim, _, ok := l.Oldest()
+	if !ok {
+		return true
+	}
+	return c.admission.Admit(key, victim)
+}
+
+// compareAndSet 在 key 当前的版本等于 expected 时写入 value, 版本 0 表示 key 不在缓存中
+// 返回写入前的版本, 写入不经过准入策略, 避免成功的写入被丢弃
+func (c *cache) compareAndSet(key string, expected uint64, value ByteView) (current uint64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l := c.lruFor(key, true)
+	// 更早代数的条目视为不存在
+	if v, exist := l.Peek(key); exist && v.(storedView).view.generation >= value.generation {
+		current = v.(storedView).view.version
+	}
+	if current != expected {
+		return current, false
+	}
+	l.Add(key, storedView{value})
+	return current, true
+}
+
+func (c *cache) setAdmission(policy AdmissionPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.admission = policy
+}
+
+func (c *cache) evicted(key string, value lru.Value) {
+	if c.onEvicted != nil {
+		c.onEvicted(key, value.(storedView).view)
+	}
+}
+
+func (c *cache) remove(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l := c.lruFor(key, false)
+	if l == nil {
+		return false
+	}
+	removed := l.Remove(key)
+	c.dropEmpty(key)
+	return removed
+}
+
+// removeOlder 在 key 的代数小于 generation 时删除, 避免误删刚写入的新值
+func (c *cache) removeOlder(key string, generation uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l := c.lruFor(key, false)
+	if l == nil {
+		return false
+	}
+	if v, ok := l.Peek(key); ok && v.(storedView).view.generation < generation {
+		removed := l.Remove(key)
+		c.dropEmpty(key)
+		return removed
+	}
+	return false
+}
+
+// dropEmpty 划分了租户时, 删除 key 所属租户已经为空的 LRU
+func (c *cache) dropEmpty(key string) {
+	if c.tenants != nil {
+		c.tenants.dropEmpty(key)
 	}
-	c.lru.Add(key, value)
 }
 
 func (c *cache) get(key string) (value ByteView, ok bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if c.lru == nil {
+	if c.admission != nil {
+		c.admission.Record(key)
+	}
+	l := c.lruFor(key, false)
+	if l == nil {
 		return
 	} else {
-		if v, ok := c.lru.Get(key); ok {
-			return v.(ByteView), ok
+		if v, ok := l.Get(key); ok {
+			return v.(storedView).view, ok
 		}
 	}
 	return
 }
+
+// peek 返回 key 的值, 不改变 LRU 中的顺序, 也不计入准入策略的访问
+func (c *cache) peek(key string) (value ByteView, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l := c.lruFor(key, false)
+	if l == nil {
+		return
+	}
+	v, ok := l.Peek(key)
+	if !ok {
+		return
+	}
+	return v.(storedView).view, true
+}
+
+// rangeEntries 遍历缓存中的所有条目, 遍历期间持有锁, fn 中不能再访问 cache
+func (c *cache) rangeEntries(fn func(key string, value ByteView) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	visit := func(key string, value lru.Value) bool {
+		return fn(key, value.(storedView).view)
+	}
+	if c.tenants != nil {
+		c.tenants.rangeEntries(visit)
+		return
+	}
+	if c.lru != nil {
+		c.lru.Range(visit)
+	}
+}
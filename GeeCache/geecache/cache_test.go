@@ -0,0 +1,124 @@
+package geecache
+
+import (
+	"fmt"
+	"geecache/tinylfu"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAdmission(t *testing.T) {
+	// 容量恰好容纳两个条目
+	ca := cache{cacheBytes: 8}
+	ca.setAdmission(tinylfu.New(64))
+	for i := 0; i < 3; i++ {
+		ca.get("k1")
+		ca.get("k2")
+	}
+	ca.add("k1", ByteView{b: []byte("v1")})
+	ca.add("k2", ByteView{b: []byte("v2")})
+
+	c.Convey("准入策略测试", t, func() {
+		ca.get("k3")
+		c.So(ca.add("k3", ByteView{b: []byte("v3")}), c.ShouldBeFalse)
+		_, ok := ca.get("k3")
+		c.So(ok, c.ShouldBeFalse)
+		_, ok = ca.get("k1")
+		c.So(ok, c.ShouldBeTrue)
+
+		// 访问次数超过淘汰对象后被接纳
+		for i := 0; i < 10; i++ {
+			ca.get("k4")
+		}
+		c.So(ca.add("k4", ByteView{b: []byte("v4")}), c.ShouldBeTrue)
+		_, ok = ca.get("k4")
+		c.So(ok, c.ShouldBeTrue)
+	})
+}
+
+func TestAdmissionRejected(t *testing.T) {
+	g := mustNewGroup(t, "admission", 8, GetterFunc(func(key string) ([]byte, error) {
+		return []byte("v" + key[1:]), nil
+	}))
+	g.SetAdmissionPolicy(tinylfu.New(64))
+	for i := 0; i < 3; i++ {
+		g.Get("k1")
+		g.Get("k2")
+	}
+	watcher := g.Watch(nil, nil)
+	defer watcher.Close()
+
+	c.Convey("被准入策略拒绝的值没有版本号, 也不通知订阅者", t, func() {
+		v, err := g.Get("k3")
+		c.So(err, c.ShouldBeNil)
+		c.So(v.String(), c.ShouldEqual, "v3")
+		c.So(v.Version(), c.ShouldEqual, 0)
+		select {
+		case e := <-watcher.Events():
+			t.Errorf("unexpected event %v", e)
+		default:
+		}
+	})
+}
+
+// zipfTrace 生成服从 Zipf 分布的访问序列, scanEvery > 0 时每隔 scanEvery 次插入一个只出现一次的 key, 模拟爬虫
+func zipfTrace(n int, keySpace uint64, scanEvery int) []string {
+	r := rand.New(rand.NewSource(1))
+	z := rand.NewZipf(r, 1.01, 1, keySpace-1)
+	trace := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if scanEvery > 0 && i%scanEvery == 0 {
+			trace = append(trace, "scan-"+strconv.Itoa(i))
+			continue
+		}
+		trace = append(trace, strconv.FormatUint(z.Uint64(), 10))
+	}
+	return trace
+}
+
+func BenchmarkHitRatio(b *testing.B) {
+	const (
+		keySpace = 100000
+		entries  = 1000 // 缓存能容纳的条目数
+	)
+	value := ByteView{b: make([]byte, 64)}
+	traces := []struct {
+		name      string
+		scanEvery int
+	}{
+		{name: "zipf", scanEvery: 0},
+		{name: "zipf+scan", scanEvery: 2},
+	}
+	policies := []struct {
+		name   string
+		policy func() AdmissionPolicy
+	}{
+		{name: "lru", policy: func() AdmissionPolicy { return nil }},
+		{name: "tinylfu", policy: func() AdmissionPolicy { return tinylfu.New(entries * 10) }},
+	}
+
+	for _, tr := range traces {
+		trace := zipfTrace(200000, keySpace, tr.scanEvery)
+		for _, p := range policies {
+			b.Run(fmt.Sprintf("%s/%s", tr.name, p.name), func(b *testing.B) {
+				var hits, total int
+				for i := 0; i < b.N; i++ {
+					ca := cache{cacheBytes: int64(entries * (value.Len() + 6))}
+					ca.setAdmission(p.policy())
+					for _, key := range trace {
+						total++
+						if _, ok := ca.get(key); ok {
+							hits++
+							continue
+						}
+						ca.add(key, value)
+					}
+				}
+				b.ReportMetric(float64(hits)/float64(total)*100, "hit%")
+			})
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package geecache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	pb "geecache/geecachepb"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const defaultCASPath = "_cas/"
+
+// nextVersion 返回本组下一个版本号, 版本号在组内单调递增
+func (g *Group) nextVersion() uint64 {
+	return atomic.AddUint64(&g.version, 1)
+}
+
+// observeVersion 保证之后分配的版本号大于 version
+func (g *Group) observeVersion(version uint64) {
+	for {
+		current := atomic.LoadUint64(&g.version)
+		if current >= version || atomic.CompareAndSwapUint64(&g.version, current, version) {
+			return
+		}
+	}
+}
+
+// GetWithVersion 返回值及其版本号, 版本号可以用于之后的 CompareAndSet
+// 与 CompareAndSet 一样从负责 key 的节点读取, 不使用热点副本, 也不受负载有界的影响
+func (g *Group) GetWithVersion(key string) (ByteView, uint64, error) {
+	if key == "" {
+		return ByteView{}, 0, fmt.Errorf("key is required")
+	}
+	ctx := context.Background()
+	var view ByteView
+	var err error
+	if peer, ok := g.pickOwner(key); ok {
+		view, err = g.getFromPeer(ctx, peer, key)
+	} else {
+		view, err = g.GetForPeer(ctx, key)
+	}
+	if err != nil {
+		return ByteView{}, 0, err
+	}
+	return view, view.Version(), nil
+}
+
+// CompareAndSet 在 key 当前的版本等于 expectedVersion 时写入 value, 返回新的版本号
+// expectedVersion 为 0 表示只在 key 不在缓存中时写入
+// 版本不一致时返回包装了 ErrVersionConflict 的错误, 以及 key 当前的版本号
+// 请求会被路由到负责 key 的节点上执行
+func (g *Group) CompareAndSet(key string, expectedVersion uint64, value []byte) (uint64, error) {
+	return g.CompareAndSetContext(context.Background(), key, expectedVersion, value)
+}
+
+func (g *Group) CompareAndSetContext(ctx context.Context, key string, expectedVersion uint64, value []byte) (uint64, error) {
+	if key == "" {
+		return 0, fmt.Errorf("key is required")
+	}
+	if err := g.checkSize(int64(len(value))); err != nil {
+		return 0, err
+	}
+
+	if peer, ok := g.pickOwner(key); ok {
+		setter, ok := peer.(PeerSetter)
+		if !ok {
+			return 0, fmt.Errorf("peer does not support CompareAndSet")
+		}
+		req := &pb.CASRequest{Group: g.name, Key: key, ExpectedVersion: expectedVersion, Value: value}
+		res := &pb.CASResponse{}
+		err := setter.CompareAndSet(ctx, req, res)
+		// 本节点上的热点副本已经过期
+		g.hotCache.remove(key)
+		return res.GetVersion(), err
+	}
+	return g.compareAndSetLocally(key, expectedVersion, value)
+}
+
+// CompareAndSetForPeer 处理其他节点转发过来的 CompareAndSet, 总是在本节点执行, 不再转发
+func (g *Group) CompareAndSetForPeer(key string, expectedVersion uint64, value []byte) (uint64, error) {
+	if key == "" {
+		return 0, fmt.Errorf("key is required")
+	}
+	if err := g.checkSize(int64(len(value))); err != nil {
+		return 0, err
+	}
+	return g.compareAndSetLocally(key, expectedVersion, value)
+}
+
+// pickOwner 返回哈希环上负责 key 的节点, PeerPicker 没有实现 OwnerPicker 时退回到 PickPeer
+func (g *Group) pickOwner(key string) (PeerGetter, bool) {
+	if g.peers == nil {
+		return nil, false
+	}
+	if picker, ok := g.peers.(OwnerPicker); ok {
+		return picker.PickOwner(key)
+	}
+	return g.peers.PickPeer(key)
+}
+
+func (g *Group) compareAndSetLocally(key string, expectedVersion uint64, value []byte) (uint64, error) {
+	view := g.newView(value)
+	view.version = g.nextVersion()
+	if current, ok := g.mainCache.compareAndSet(key, expectedVersion, view); !ok {
+		return current, fmt.Errorf("%s: expected version %d, current %d: %w", key, expectedVersion, current, ErrVersionConflict)
+	}
+	g.watchers.publish(EventSet, key)
+	return view.version, nil
+}
+
+// serveCompareAndSet 处理其他节点转发过来的 CompareAndSet, 总是在本节点执行, 版本不一致时返回 409 和当前版本
+func (p *HTTPPool) serveCompareAndSet(w http.ResponseWriter, r *http.Request, groupName string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	group := GetGroup(groupName)
+	if group == nil {
+		http.Error(w, "No such group: "+groupName, http.StatusNotFound)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxWriteBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	req := &pb.CASRequest{}
+	if err := proto.Unmarshal(body, req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	code := http.StatusOK
+	version, err := group.CompareAndSetForPeer(req.GetKey(), req.GetExpectedVersion(), req.GetValue())
+	switch {
+	case errors.Is(err, ErrVersionConflict):
+		code = http.StatusConflict
+	case errors.Is(err, ErrValueTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body, err = proto.Marshal(&pb.CASResponse{Version: version})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
+func (h *httpGetter) CompareAndSet(ctx context.Context, in *pb.CASRequest, out *pb.CASResponse) error {
+	body, err := proto.Marshal(in)
+	if err != nil {
+		return err
+	}
+	u := fmt.Sprintf("%v%v%v", h.baseURL, defaultCASPath, url.QueryEscape(in.GetGroup()))
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusRequestEntityTooLarge {
+		return fmt.Errorf("%s: %w", in.GetKey(), ErrValueTooLarge)
+	}
+	if res.StatusCode == http.StatusServiceUnavailable {
+		h.backoff(res.Header.Get("Retry-After"))
+		return fmt.Errorf("%s: %w", in.GetKey(), ErrPeerOverloaded)
+	}
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusConflict {
+		return fmt.Errorf("server returned: %v", res.Status)
+	}
+	if body, err = ioutil.ReadAll(res.Body); err != nil {
+		return fmt.Errorf("reading response body: %v", err)
+	}
+	if err = proto.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decoding response body: %v", err)
+	}
+	if res.StatusCode == http.StatusConflict {
+		return fmt.Errorf("%s: expected version %d, current %d: %w", in.GetKey(), in.GetExpectedVersion(), out.GetVersion(), ErrVersionConflict)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package geecache
+
+import (
+	"context"
+	"errors"
+	pb "geecache/geecachepb"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCompareAndSet(t *testing.T) {
+	g := mustNewGroup(t, "cas", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte("loaded:" + key), nil
+	}))
+
+	c.Convey("版本一致时写入, 否则返回冲突", t, func() {
+		v1, err := g.CompareAndSet("Tom", 0, []byte("630"))
+		c.So(err, c.ShouldBeNil)
+		c.So(v1, c.ShouldBeGreaterThan, 0)
+
+		// 版本 0 只在 key 不存在时写入
+		current, err := g.CompareAndSet("Tom", 0, []byte("631"))
+		c.So(errors.Is(err, ErrVersionConflict), c.ShouldBeTrue)
+		c.So(current, c.ShouldEqual, v1)
+
+		v2, err := g.CompareAndSet("Tom", v1, []byte("632"))
+		c.So(err, c.ShouldBeNil)
+		c.So(v2, c.ShouldBeGreaterThan, v1)
+
+		view, version, err := g.GetWithVersion("Tom")
+		c.So(err, c.ShouldBeNil)
+		c.So(view.String(), c.ShouldEqual, "632")
+		c.So(version, c.ShouldEqual, v2)
+
+		// 从数据源加载的值同样有版本号
+		_, version, err = g.GetWithVersion("Jack")
+		c.So(err, c.ShouldBeNil)
+		c.So(version, c.ShouldBeGreaterThan, v2)
+	})
+}
+
+func TestHTTPCompareAndSet(t *testing.T) {
+	g := mustNewGroup(t, "cas-http", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}))
+	g.SetMaxValueSize(8)
+	srv := httptest.NewServer(NewHTTPPool(""))
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + defaultBasePath}
+
+	c.Convey("通过 HTTP 转发 CompareAndSet", t, func() {
+		res := &pb.CASResponse{}
+		err := getter.CompareAndSet(context.Background(), &pb.CASRequest{Group: "cas-http", Key: "Tom", Value: []byte("630")}, res)
+		c.So(err, c.ShouldBeNil)
+		version := res.GetVersion()
+
+		res = &pb.CASResponse{}
+		err = getter.CompareAndSet(context.Background(), &pb.CASRequest{Group: "cas-http", Key: "Tom", ExpectedVersion: version + 1, Value: []byte("631")}, res)
+		c.So(errors.Is(err, ErrVersionConflict), c.ShouldBeTrue)
+		c.So(res.GetVersion(), c.ShouldEqual, version)
+
+		err = getter.CompareAndSet(context.Background(), &pb.CASRequest{Group: "cas-http", Key: "Tom", ExpectedVersion: version, Value: []byte("too large value")}, &pb.CASResponse{})
+		c.So(errors.Is(err, ErrValueTooLarge), c.ShouldBeTrue)
+
+		// 请求体大小有上限
+		r := httptest.NewRequest(http.MethodPost, defaultBasePath+defaultCASPath+"cas-http", strings.NewReader(strings.Repeat("x", maxWriteBody+1)))
+		w := httptest.NewRecorder()
+		NewHTTPPool("").ServeHTTP(w, r)
+		c.So(w.Code, c.ShouldEqual, http.StatusRequestEntityTooLarge)
+
+		out := &pb.Response{}
+		c.So(getter.Get(context.Background(), &pb.Request{Group: "cas-http", Key: "Tom"}, out), c.ShouldBeNil)
+		c.So(string(out.GetValue()), c.ShouldEqual, "630")
+		c.So(out.GetVersion(), c.ShouldEqual, version)
+	})
+}
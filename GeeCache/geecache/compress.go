@@ -0,0 +1,173 @@
+package geecache
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// acceptCodecHeader 列出请求方能够解压的编码, 服务端据此直接返回压缩后的值
+const acceptCodecHeader = "X-Geecache-Accept-Codec"
+
+// Codec 压缩缓存值, 节点之间通过 Name 识别同一种编码
+type Codec interface {
+	Name() string
+	Encode(b []byte) ([]byte, error)
+	NewReader(r io.Reader) (io.Reader, error)
+}
+
+var (
+	// Gzip 压缩率较高
+	Gzip Codec = gzipCodec{level: gzip.DefaultCompression}
+	// Flate 使用最快的 LZ77 压缩级别, 适合对延迟敏感的场景
+	Flate Codec = flateCodec{level: flate.BestSpeed}
+)
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{Gzip.Name(): Gzip, Flate.Name(): Flate}
+)
+
+// RegisterCodec 注册自定义编码, 只有注册过的编码才会在节点之间直接传输
+func RegisterCodec(codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[codec.Name()] = codec
+}
+
+func codecByName(name string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	codec, ok := codecs[name]
+	return codec, ok
+}
+
+// codecNames 返回所有已注册编码的名字
+func codecNames() []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// acceptsCodec 判断 accept 中是否包含 name
+func acceptsCodec(accept []string, name string) bool {
+	for _, a := range accept {
+		if strings.TrimSpace(a) == name {
+			return true
+		}
+	}
+	return false
+}
+
+type gzipCodec struct {
+	level int
+}
+
+func (c gzipCodec) Name() string { return "gzip" }
+
+func (c gzipCodec) Encode(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, c.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c gzipCodec) NewReader(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+type flateCodec struct {
+	level int
+}
+
+func (c flateCodec) Name() string { return "flate" }
+
+func (c flateCodec) Encode(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, c.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c flateCodec) NewReader(r io.Reader) (io.Reader, error) {
+	return flate.NewReader(r), nil
+}
+
+// errReader 在每次读取时返回同一个错误
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// sizedReader 检查解压后的长度恰好为 size, 最多多读 1 字节, 用于发现声明的大小不正确的值
+type sizedReader struct {
+	r    io.Reader
+	size int64
+	read int64
+}
+
+func newSizedReader(r io.Reader, size int64) *sizedReader {
+	return &sizedReader{r: io.LimitReader(r, size+1), size: size}
+}
+
+func (r *sizedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if r.read > r.size {
+		return 0, fmt.Errorf("decompressed size exceeds %d", r.size)
+	}
+	if err == io.EOF && r.read < r.size {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// SetCompression 对不小于 threshold 字节的值使用 codec 压缩后存储, 缓存大小按压缩后的字节数计算
+// 压缩后没有变小的值仍按原样存储, codec 为 nil 时关闭压缩, 需要在开始服务之前调用
+func (g *Group) SetCompression(codec Codec, threshold int) {
+	g.codec = codec
+	g.compressThreshold = threshold
+}
+
+// newView 按本组的压缩和分块设置构造属于当前代数的 ByteView
+func (g *Group) newView(b []byte) ByteView {
+	view := newByteView(b, g.chunkSize)
+	if g.codec != nil && len(b) >= g.compressThreshold {
+		if compressed, err := g.codec.Encode(b); err == nil && len(compressed) < len(b) {
+			view = newByteView(compressed, g.chunkSize)
+			view.codec = g.codec
+			view.n = len(b)
+		}
+	}
+	view.generation = g.Generation()
+	return view
+}
@@ -0,0 +1,116 @@
+package geecache
+
+import (
+	"context"
+	pb "geecache/geecachepb"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+var jsonValue = strings.Repeat(`{"name":"Tom","score":630},`, 40)
+
+func TestCompression(t *testing.T) {
+	for _, codec := range []Codec{Gzip, Flate} {
+		g := mustNewGroup(t, "compress-"+codec.Name(), 2<<10, GetterFunc(func(key string) ([]byte, error) {
+			if key == "small" {
+				return []byte("630"), nil
+			}
+			return []byte(jsonValue), nil
+		}))
+		g.SetCompression(codec, 64)
+		g.SetChunkSize(16)
+
+		c.Convey("按压缩后的大小计算缓存大小: "+codec.Name(), t, func() {
+			for _, key := range []string{"a", "b", "c", "d"} {
+				view, err := g.Get(key)
+				c.So(err, c.ShouldBeNil)
+				c.So(view.Len(), c.ShouldEqual, len(jsonValue))
+				c.So(view.String(), c.ShouldEqual, jsonValue)
+			}
+			// 未压缩时 2KB 只能放下一个值
+			c.So(g.mainCache.lru.Len(), c.ShouldEqual, 4)
+			c.So(g.mainCache.lru.Bytes(), c.ShouldBeLessThan, len(jsonValue))
+
+			view, err := g.Get("small")
+			c.So(err, c.ShouldBeNil)
+			c.So(view.codec, c.ShouldBeNil)
+			c.So(view.String(), c.ShouldEqual, "630")
+		})
+	}
+}
+
+func TestHTTPCompression(t *testing.T) {
+	g := mustNewGroup(t, "compress-http", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(jsonValue), nil
+	}))
+	g.SetCompression(Gzip, 64)
+	srv := httptest.NewServer(NewHTTPPool(""))
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + defaultBasePath}
+
+	c.Convey("只向支持该编码的节点发送压缩后的数据", t, func() {
+		res := &pb.Response{}
+		err := getter.Get(context.Background(), &pb.Request{Group: "compress-http", Key: "Tom", AcceptCodecs: []string{"gzip"}}, res)
+		c.So(err, c.ShouldBeNil)
+		c.So(res.GetCodec(), c.ShouldEqual, "gzip")
+		c.So(res.GetRawSize(), c.ShouldEqual, len(jsonValue))
+		c.So(len(res.GetValue()), c.ShouldBeLessThan, len(jsonValue))
+
+		res = &pb.Response{}
+		err = getter.Get(context.Background(), &pb.Request{Group: "compress-http", Key: "Tom", AcceptCodecs: []string{"flate"}}, res)
+		c.So(err, c.ShouldBeNil)
+		c.So(res.GetCodec(), c.ShouldBeEmpty)
+		c.So(string(res.GetValue()), c.ShouldEqual, jsonValue)
+
+		remote := mustNewGroup(t, "compress-http-local", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+			return nil, ErrNotFound
+		}))
+		remote.RegisterPeers(&renamePeers{getter: getter, group: "compress-http"})
+		view, err := remote.Get("Tom")
+		c.So(err, c.ShouldBeNil)
+		c.So(view.codec.Name(), c.ShouldEqual, "gzip")
+		c.So(view.Len(), c.ShouldEqual, len(jsonValue))
+		c.So(view.String(), c.ShouldEqual, jsonValue)
+	})
+}
+
+func TestHTTPCompressionChunked(t *testing.T) {
+	g := mustNewGroup(t, "compress-chunked", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(jsonValue), nil
+	}))
+	g.SetCompression(Gzip, 64)
+	g.SetChunkSize(16)
+	srv := httptest.NewServer(NewHTTPPool(""))
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + defaultBasePath}
+
+	c.Convey("分块存储的压缩值也以流的方式发送", t, func() {
+		res := &pb.Response{}
+		err := getter.Get(context.Background(), &pb.Request{Group: "compress-chunked", Key: "Tom", AcceptCodecs: []string{"gzip"}}, res)
+		c.So(err, c.ShouldBeNil)
+		c.So(res.GetCodec(), c.ShouldEqual, "gzip")
+		c.So(res.GetRawSize(), c.ShouldEqual, len(jsonValue))
+		c.So(len(res.GetChunks()), c.ShouldBeGreaterThan, 1)
+
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+defaultBasePath+"compress-chunked/Tom", nil)
+		req.Header.Set(acceptCodecHeader, "gzip")
+		hres, err := http.DefaultClient.Do(req)
+		c.So(err, c.ShouldBeNil)
+		hres.Body.Close()
+		c.So(hres.Header.Get("Content-Type"), c.ShouldEqual, streamContentType)
+		c.So(hres.Header.Get(codecHeader), c.ShouldEqual, "gzip")
+
+		remote := mustNewGroup(t, "compress-chunked-local", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+			return nil, ErrNotFound
+		}))
+		remote.RegisterPeers(&renamePeers{getter: getter, group: "compress-chunked"})
+		view, err := remote.Get("Tom")
+		c.So(err, c.ShouldBeNil)
+		c.So(view.codec.Name(), c.ShouldEqual, "gzip")
+		c.So(view.String(), c.ShouldEqual, jsonValue)
+	})
+}
@@ -2,6 +2,7 @@ package consistenthash
 
 import (
 	"hash/crc32"
+	"math"
 	"sort"
 	"strconv"
 )
@@ -13,6 +14,7 @@ type Map struct {
 	replicas int            // 虚拟节点副本数
 	keys     []int          // 哈希环
 	hashMap  map[int]string // 虚拟节点和真实节点的映射表, key: 虚拟节点的 Hash 值, value: 真实节点的名称
+	nodes    []string       // 真实节点
 }
 
 func New(replicas int, fn Hash) *Map {
@@ -31,6 +33,7 @@ func New(replicas int, fn Hash) *Map {
 func (m *Map) Add(keys ...string) {
 	// 添加真实节点
 	for _, key := range keys {
+		m.nodes = append(m.nodes, key)
 		for i := 0; i < m.replicas; i++ {
 			// 计算虚拟节点的 Hash 值
 			hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
@@ -59,3 +62,50 @@ func (m *Map) Get(key string) string {
 	return m.hashMap[m.keys[idx%len(m.keys)]]
 
 }
+
+// Nodes 返回所有真实节点
+func (m *Map) Nodes() []string {
+	return m.nodes
+}
+
+// GetBounded 实现负载有界的一致性哈希 (consistent hashing with bounded loads)
+// 从 key 在哈希环上的位置开始顺时针查找, 跳过负载已达到 (1+epsilon) 倍平均负载的节点
+// load 返回真实节点当前的负载, 例如进行中的请求数
+// self 是调用方自身, 它不会给自己发请求, 因此不计入平均负载, 查找到它时总是选中; 为空时所有节点都计入
+func (m *Map) GetBounded(key string, load func(node string) int64, epsilon float64, self string) string {
+	if len(m.keys) == 0 {
+		return ""
+	}
+
+	var total, count int64
+	for _, node := range m.nodes {
+		if node == self {
+			continue
+		}
+		total += load(node)
+		count++
+	}
+	if count == 0 {
+		return m.Get(key)
+	}
+	// 加上即将分配的这一个请求后, 每个节点允许的最大负载
+	capacity := int64(math.Ceil((1 + epsilon) * float64(total+1) / float64(count)))
+
+	hash := int(m.hash([]byte(key)))
+	idx := sort.Search(len(m.keys), func(i int) bool {
+		return m.keys[i] >= hash
+	})
+
+	visited := make(map[string]bool, len(m.nodes))
+	for i := 0; i < len(m.keys) && len(visited) < len(m.nodes); i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if visited[node] {
+			continue
+		}
+		visited[node] = true
+		if node == self || load(node)+1 <= capacity {
+			return node
+		}
+	}
+	return m.hashMap[m.keys[idx%len(m.keys)]]
+}
@@ -39,3 +39,46 @@ func TestHashing(t *testing.T) {
 		}
 	})
 }
+
+func TestBoundedLoad(t *testing.T) {
+	hash := initHashMap()
+	loads := map[string]int64{"2": 0, "4": 0, "6": 0}
+	load := func(node string) int64 {
+		return loads[node]
+	}
+
+	c.Convey("负载有界测试", t, func() {
+		c.So(hash.GetBounded("11", load, 0.25, ""), c.ShouldEqual, "2")
+
+		// 2 的负载超过平均值的 1.25 倍, 顺时针跳到下一个节点 4
+		loads["2"] = 3
+		c.So(hash.GetBounded("11", load, 0.25, ""), c.ShouldEqual, "4")
+
+		// 4 也过载, 继续跳到 6
+		loads["4"] = 3
+		c.So(hash.GetBounded("11", load, 0.25, ""), c.ShouldEqual, "6")
+
+		// 负载均衡时仍然使用原来的节点
+		loads["6"] = 3
+		c.So(hash.GetBounded("11", load, 0.25, ""), c.ShouldEqual, "2")
+	})
+}
+
+func TestBoundedLoadSelf(t *testing.T) {
+	hash := initHashMap()
+	// 本节点 6 不给自己发请求, 负载总是 0
+	loads := map[string]int64{"2": 3, "4": 3, "6": 0}
+	load := func(node string) int64 {
+		return loads[node]
+	}
+
+	c.Convey("调用方自身不计入平均负载", t, func() {
+		// 计入 6 时平均负载被拉低, 2 会被跳过
+		c.So(hash.GetBounded("11", load, 0.25, ""), c.ShouldNotEqual, "2")
+		c.So(hash.GetBounded("11", load, 0.25, "6"), c.ShouldEqual, "2")
+
+		// 2 明显高于其他远程节点时仍然被跳过
+		loads["2"] = 10
+		c.So(hash.GetBounded("11", load, 0.25, "6"), c.ShouldEqual, "4")
+	})
+}
@@ -0,0 +1,21 @@
+package geecache
+
+import (
+	"context"
+	"time"
+)
+
+// detachedContext 保留 parent 中的值 (如 trace), 但不会随 parent 取消或超时
+// singleflight 的加载结果由所有等待的请求共享, 不能因为第一个请求被取消而让其他请求一起失败
+type detachedContext struct {
+	parent context.Context
+}
+
+func detach(ctx context.Context) context.Context {
+	return detachedContext{ctx}
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) { return }
+func (detachedContext) Done() <-chan struct{}                   { return nil }
+func (detachedContext) Err() error                              { return nil }
+func (c detachedContext) Value(key interface{}) interface{}     { return c.parent.Value(key) }
@@ -0,0 +1,19 @@
+package geecache
+
+import "errors"
+
+// ErrNotFound 表示数据源中不存在该 key, Getter 应当返回 (或包装) 该错误
+// 远程节点返回 404 时也会转换为 ErrNotFound, 此时不会再回退到本地加载
+var ErrNotFound = errors.New("geecache: key not found")
+
+// ErrValueTooLarge 表示值超过了 Group 允许的最大值
+var ErrValueTooLarge = errors.New("geecache: value too large")
+
+// ErrVersionConflict 表示 CompareAndSet 时 key 的当前版本与期望的版本不一致
+var ErrVersionConflict = errors.New("geecache: version conflict")
+
+// ErrPeerOverloaded 表示远程节点过载拒绝了请求, Get 时在本地加载, 不再重试, 写入时返回给调用方
+var ErrPeerOverloaded = errors.New("geecache: peer overloaded")
+
+// ErrGroupExists 表示同名的 Group 已经存在, 需要先调用 DestroyGroup
+var ErrGroupExists = errors.New("geecache: group already exists")
@@ -0,0 +1,310 @@
+// Package geecachetest 在一个进程内搭建由多个节点组成的 GeeCache 集群, 用于测试节点间的行为
+// 节点之间通过内存中的 PeerPicker 和 PeerGetter 通信, 可以注入延迟, 丢包和网络分区
+package geecachetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"geecache"
+	consistenthash "geecache/consistenhash"
+	pb "geecache/geecachepb"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrPartitioned 表示两个节点之间被分区
+	ErrPartitioned = errors.New("geecachetest: nodes are partitioned")
+	// ErrDropped 表示请求被丢弃
+	ErrDropped = errors.New("geecachetest: request dropped")
+)
+
+// clusterSeq 保证每个集群的 Group 名字不同
+var clusterSeq int64
+
+// link 是从 from 到 to 的有向连接
+type link struct {
+	from, to int
+}
+
+// linkConfig 描述一条连接上注入的故障
+type linkConfig struct {
+	latency     time.Duration
+	dropRate    float64
+	partitioned bool
+}
+
+// Node 是集群中的一个节点
+type Node struct {
+	Index int
+	Name  string
+	Group *geecache.Group
+
+	mu    sync.Mutex
+	calls map[string]int // 每个 key 调用 Getter 的次数
+}
+
+// Calls 返回本节点为 key 调用 Getter 的次数
+func (n *Node) Calls(key string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls[key]
+}
+
+// TotalCalls 返回本节点调用 Getter 的总次数
+func (n *Node) TotalCalls() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	total := 0
+	for _, c := range n.calls {
+		total += c
+	}
+	return total
+}
+
+// Cluster 是由多个节点组成的内存集群
+type Cluster struct {
+	Nodes []*Node
+
+	ring     *consistenthash.Map
+	mu       sync.Mutex
+	links    map[link]linkConfig
+	requests map[link]int
+	inflight map[link]int64 // 进行中的请求数, 作为负载有界哈希的负载
+	epsilon  float64        // 大于 0 时按负载有界的一致性哈希选择节点
+	rand     *rand.Rand
+}
+
+// NewCluster 创建 n 个节点, 每个节点都有一个使用 getter 作为数据源的 Group
+func NewCluster(n int, cacheBytes int64, getter geecache.Getter) *Cluster {
+	seq := atomic.AddInt64(&clusterSeq, 1)
+	c := &Cluster{
+		ring:     consistenthash.New(50, nil),
+		links:    make(map[link]linkConfig),
+		requests: make(map[link]int),
+		inflight: make(map[link]int64),
+		rand:     rand.New(rand.NewSource(1)),
+	}
+
+	names := make([]string, n)
+	for i := 0; i < n; i++ {
+		node := &Node{
+			Index: i,
+			Name:  "node" + strconv.Itoa(i),
+			calls: make(map[string]int),
+		}
+		names[i] = node.Name
+		group, err := geecache.NewGroup(fmt.Sprintf("geecachetest-%d-%s", seq, node.Name), cacheBytes, geecache.GetterFunc(
+			func(key string) ([]byte, error) {
+				node.mu.Lock()
+				node.calls[key]++
+				node.mu.Unlock()
+				return getter.Get(key)
+			}), nil)
+		if err != nil {
+			// 名字中带有集群序号, 不会重复
+			panic(err)
+		}
+		node.Group = group
+		c.Nodes = append(c.Nodes, node)
+	}
+	c.ring.Add(names...)
+
+	for _, node := range c.Nodes {
+		node.Group.RegisterPeers(&picker{cluster: c, self: node.Index})
+	}
+	return c
+}
+
+// Node 返回第 i 个节点
+func (c *Cluster) Node(i int) *Node {
+	return c.Nodes[i]
+}
+
+// Owner 返回负责 key 的节点
+func (c *Cluster) Owner(key string) *Node {
+	return c.nodeByName(c.ring.Get(key))
+}
+
+func (c *Cluster) nodeByName(name string) *Node {
+	for _, node := range c.Nodes {
+		if node.Name == name {
+			return node
+		}
+	}
+	return nil
+}
+
+// SetLatency 为从 from 到 to 的请求增加延迟
+func (c *Cluster) SetLatency(from, to int, d time.Duration) {
+	c.updateLink(from, to, func(l *linkConfig) { l.latency = d })
+}
+
+// SetDropRate 按比例丢弃从 from 到 to 的请求, 随机数种子固定, 结果可以复现
+func (c *Cluster) SetDropRate(from, to int, rate float64) {
+	c.updateLink(from, to, func(l *linkConfig) { l.dropRate = rate })
+}
+
+// Partition 断开 a 和 b 之间双向的连接
+func (c *Cluster) Partition(a, b int) {
+	c.updateLink(a, b, func(l *linkConfig) { l.partitioned = true })
+	c.updateLink(b, a, func(l *linkConfig) { l.partitioned = true })
+}
+
+// SetBoundedLoad 与 HTTPPool.SetBoundedLoad 相同, 按发出的请求中进行中的数量选择节点
+func (c *Cluster) SetBoundedLoad(epsilon float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.epsilon = epsilon
+}
+
+// Heal 清除所有注入的故障
+func (c *Cluster) Heal() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.links = make(map[link]linkConfig)
+}
+
+// Requests 返回从 from 发往 to 的请求数, 包括失败的请求
+func (c *Cluster) Requests(from, to int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.requests[link{from, to}]
+}
+
+func (c *Cluster) updateLink(from, to int, fn func(l *linkConfig)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l := c.links[link{from, to}]
+	fn(&l)
+	c.links[link{from, to}] = l
+}
+
+// send 模拟一次从 from 到 to 的请求
+func (c *Cluster) send(ctx context.Context, from, to int, in *pb.Request, out *pb.Response) error {
+	if err := c.transport(ctx, from, to); err != nil {
+		return err
+	}
+
+	c.Nodes[to].Group.SetGeneration(in.GetGeneration())
+	view, err := c.Nodes[to].Group.GetForPeer(ctx, in.GetKey())
+	if err != nil {
+		return err
+	}
+	out.Value, err = view.Bytes()
+	if err != nil {
+		return err
+	}
+	out.Version = view.Version()
+	return nil
+}
+
+// transport 模拟链路上的延迟和故障, 并记录请求数
+func (c *Cluster) transport(ctx context.Context, from, to int) error {
+	c.mu.Lock()
+	c.requests[link{from, to}]++
+	c.inflight[link{from, to}]++
+	l := c.links[link{from, to}]
+	dropped := l.dropRate > 0 && c.rand.Float64() < l.dropRate
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.inflight[link{from, to}]--
+		c.mu.Unlock()
+	}()
+
+	if l.latency > 0 {
+		select {
+		case <-time.After(l.latency):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if l.partitioned {
+		return ErrPartitioned
+	}
+	if dropped {
+		return ErrDropped
+	}
+	return nil
+}
+
+// picker 使用与 HTTPPool 相同的一致性哈希选择节点
+type picker struct {
+	cluster *Cluster
+	self    int
+}
+
+func (p *picker) PickPeer(key string) (geecache.PeerGetter, bool) {
+	c := p.cluster
+	c.mu.Lock()
+	epsilon := c.epsilon
+	c.mu.Unlock()
+
+	owner := c.Owner(key)
+	if epsilon > 0 {
+		owner = c.nodeByName(c.ring.GetBounded(key, p.load, epsilon, c.Nodes[p.self].Name))
+	}
+	if owner == nil || owner.Index == p.self {
+		return nil, false
+	}
+	return &getter{cluster: c, from: p.self, to: owner.Index}, true
+}
+
+// PickOwner 返回哈希环上负责 key 的节点, 不受负载有界的影响
+func (p *picker) PickOwner(key string) (geecache.PeerGetter, bool) {
+	owner := p.cluster.Owner(key)
+	if owner == nil || owner.Index == p.self {
+		return nil, false
+	}
+	return &getter{cluster: p.cluster, from: p.self, to: owner.Index}, true
+}
+
+// load 返回本节点发往 node 且进行中的请求数
+func (p *picker) load(name string) int64 {
+	node := p.cluster.nodeByName(name)
+	if node == nil {
+		return 0
+	}
+	p.cluster.mu.Lock()
+	defer p.cluster.mu.Unlock()
+	return p.cluster.inflight[link{p.self, node.Index}]
+}
+
+// getter 是从 from 到 to 的内存连接
+type getter struct {
+	cluster  *Cluster
+	from, to int
+}
+
+func (g *getter) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	return g.cluster.send(ctx, g.from, g.to, in, out)
+}
+
+func (g *getter) CompareAndSet(ctx context.Context, in *pb.CASRequest, out *pb.CASResponse) error {
+	if err := g.cluster.transport(ctx, g.from, g.to); err != nil {
+		return err
+	}
+	version, err := g.cluster.Nodes[g.to].Group.CompareAndSetForPeer(in.GetKey(), in.GetExpectedVersion(), in.GetValue())
+	out.Version = version
+	return err
+}
+
+func (g *getter) Put(ctx context.Context, group string, key string, value []byte) error {
+	if err := g.cluster.transport(ctx, g.from, g.to); err != nil {
+		return err
+	}
+	return g.cluster.Nodes[g.to].Group.Set(key, value)
+}
+
+func (g *getter) Delete(ctx context.Context, group string, key string) error {
+	if err := g.cluster.transport(ctx, g.from, g.to); err != nil {
+		return err
+	}
+	g.cluster.Nodes[g.to].Group.Remove(key)
+	return nil
+}
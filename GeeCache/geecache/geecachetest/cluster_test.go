@@ -0,0 +1,314 @@
+package geecachetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"geecache"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+var source = geecache.GetterFunc(func(key string) ([]byte, error) {
+	if key == "missing" {
+		return nil, fmt.Errorf("%s: %w", key, geecache.ErrNotFound)
+	}
+	return []byte("value:" + key), nil
+})
+
+// remoteKey 返回一个不由 node 负责的 key
+func remoteKey(cluster *Cluster, node int) string {
+	for i := 0; ; i++ {
+		key := "key" + strconv.Itoa(i)
+		if cluster.Owner(key).Index != node {
+			return key
+		}
+	}
+}
+
+func TestRouting(t *testing.T) {
+	cluster := NewCluster(3, 2<<10, source)
+	key := remoteKey(cluster, 0)
+	owner := cluster.Owner(key)
+
+	c.Convey("请求被路由到负责的节点", t, func() {
+		for i := 0; i < 3; i++ {
+			v, err := cluster.Node(i).Group.Get(key)
+			c.So(err, c.ShouldBeNil)
+			c.So(v.String(), c.ShouldEqual, "value:"+key)
+		}
+		for _, node := range cluster.Nodes {
+			if node == owner {
+				c.So(node.Calls(key), c.ShouldEqual, 1)
+			} else {
+				c.So(node.TotalCalls(), c.ShouldEqual, 0)
+			}
+		}
+		c.So(cluster.Requests(0, owner.Index), c.ShouldEqual, 1)
+
+		_, err := cluster.Node(0).Group.Get("missing")
+		c.So(errors.Is(err, geecache.ErrNotFound), c.ShouldBeTrue)
+	})
+}
+
+func TestFallback(t *testing.T) {
+	cluster := NewCluster(3, 2<<10, source)
+	key := remoteKey(cluster, 0)
+	owner := cluster.Owner(key)
+
+	c.Convey("远程节点不可用时在本地加载", t, func() {
+		cluster.Partition(0, owner.Index)
+		v, err := cluster.Node(0).Group.Get(key)
+		c.So(err, c.ShouldBeNil)
+		c.So(v.String(), c.ShouldEqual, "value:"+key)
+		c.So(cluster.Node(0).Calls(key), c.ShouldEqual, 1)
+		c.So(owner.Calls(key), c.ShouldEqual, 0)
+
+		cluster.Heal()
+		cluster.SetDropRate(1, cluster.Owner(remoteKey(cluster, 1)).Index, 1)
+		_, err = cluster.Node(1).Group.Get(remoteKey(cluster, 1))
+		c.So(err, c.ShouldBeNil)
+		c.So(cluster.Node(1).TotalCalls(), c.ShouldEqual, 1)
+	})
+}
+
+func TestSingleflight(t *testing.T) {
+	cluster := NewCluster(3, 2<<10, source)
+	key := remoteKey(cluster, 0)
+	owner := cluster.Owner(key)
+	cluster.SetLatency(0, owner.Index, 50*time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cluster.Node(0).Group.Get(key)
+		}()
+	}
+	wg.Wait()
+
+	c.Convey("并发请求只发出一次远程请求", t, func() {
+		c.So(cluster.Requests(0, owner.Index), c.ShouldEqual, 1)
+		c.So(owner.Calls(key), c.ShouldEqual, 1)
+		c.So(cluster.Node(0).TotalCalls(), c.ShouldEqual, 0)
+	})
+}
+
+func TestCompareAndSet(t *testing.T) {
+	cluster := NewCluster(3, 2<<10, source)
+	key := remoteKey(cluster, 0)
+	owner := cluster.Owner(key)
+
+	c.Convey("CompareAndSet 在负责的节点上执行", t, func() {
+		_, version, err := cluster.Node(0).Group.GetWithVersion(key)
+		c.So(err, c.ShouldBeNil)
+		c.So(version, c.ShouldBeGreaterThan, 0)
+
+		next, err := cluster.Node(0).Group.CompareAndSet(key, version, []byte("v2"))
+		c.So(err, c.ShouldBeNil)
+		c.So(cluster.Requests(0, owner.Index), c.ShouldEqual, 2)
+
+		// 其他节点使用过期的版本会冲突
+		other := cluster.Node((owner.Index + 1) % 3)
+		if other.Index == 0 {
+			other = cluster.Node((owner.Index + 2) % 3)
+		}
+		current, err := other.Group.CompareAndSet(key, version, []byte("v3"))
+		c.So(errors.Is(err, geecache.ErrVersionConflict), c.ShouldBeTrue)
+		c.So(current, c.ShouldEqual, next)
+
+		v, err := owner.Group.Get(key)
+		c.So(err, c.ShouldBeNil)
+		c.So(v.String(), c.ShouldEqual, "v2")
+		c.So(owner.Calls(key), c.ShouldEqual, 1)
+	})
+}
+
+func TestPreload(t *testing.T) {
+	cluster := NewCluster(3, 2<<10, source)
+	keys := make([]string, 30)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+	}
+
+	c.Convey("预热的 key 由负责的节点加载", t, func() {
+		p := cluster.Node(0).Group.Preload(keys).Wait()
+		c.So(p.Loaded, c.ShouldEqual, len(keys))
+		for _, key := range keys {
+			c.So(cluster.Owner(key).Calls(key), c.ShouldEqual, 1)
+		}
+		total := 0
+		for _, node := range cluster.Nodes {
+			total += node.TotalCalls()
+		}
+		c.So(total, c.ShouldEqual, len(keys))
+	})
+}
+
+func TestFlushGeneration(t *testing.T) {
+	cluster := NewCluster(3, 2<<10, source)
+	key := remoteKey(cluster, 0)
+	owner := cluster.Owner(key)
+
+	c.Convey("错过 Flush 的节点在收到代数更大的请求后追上", t, func() {
+		cluster.Node(0).Group.Get(key)
+		c.So(owner.Calls(key), c.ShouldEqual, 1)
+
+		cluster.Node(0).Group.Flush()
+		cluster.Node(0).Group.Get(key)
+		c.So(owner.Group.Generation(), c.ShouldEqual, 1)
+		c.So(owner.Calls(key), c.ShouldEqual, 2)
+	})
+}
+
+func TestBoundedLoadOneHop(t *testing.T) {
+	cluster := NewCluster(3, 2<<10, source)
+
+	// key 的负责节点过载时, 会被交给另一个既不是负责节点也不是请求方的节点
+	var key string
+	var owner, fallback *Node
+	for i := 0; fallback == nil; i++ {
+		key = "key" + strconv.Itoa(i)
+		owner = cluster.Owner(key)
+		if owner.Index == 0 {
+			continue
+		}
+		name := cluster.ring.GetBounded(key, func(node string) int64 {
+			if node == owner.Name {
+				return 100
+			}
+			return 0
+		}, 0.25, cluster.Node(0).Name)
+		if n := cluster.nodeByName(name); n != owner && n.Index != 0 {
+			fallback = n
+		}
+	}
+
+	// 让 4 个发往负责节点的请求保持进行中
+	defer saturate(cluster, 0, owner, 4)()
+	cluster.SetBoundedLoad(0.25)
+
+	total := func() int {
+		n := 0
+		for from := range cluster.Nodes {
+			for to := range cluster.Nodes {
+				n += cluster.Requests(from, to)
+			}
+		}
+		return n
+	}
+
+	c.Convey("负责节点过载时只经过一跳", t, func() {
+		before := total()
+		v, err := cluster.Node(0).Group.Get(key)
+		c.So(err, c.ShouldBeNil)
+		c.So(v.String(), c.ShouldEqual, "value:"+key)
+		c.So(total()-before, c.ShouldEqual, 1)
+		c.So(cluster.Requests(0, fallback.Index), c.ShouldEqual, 1)
+		c.So(fallback.Calls(key), c.ShouldEqual, 1)
+		c.So(owner.Calls(key), c.ShouldEqual, 0)
+	})
+}
+
+func TestBoundedLoadBalanced(t *testing.T) {
+	cluster := NewCluster(3, 2<<10, source)
+	key := remoteKey(cluster, 0)
+	owner := cluster.Owner(key)
+
+	// 发往两个远程节点的请求一样多, 本节点自身的负载不应拉低平均值
+	for _, node := range cluster.Nodes {
+		if node.Index != 0 {
+			defer saturate(cluster, 0, node, 3)()
+		}
+	}
+	cluster.SetBoundedLoad(0.25)
+
+	c.Convey("负载均衡时仍然选择负责的节点", t, func() {
+		before := cluster.Requests(0, owner.Index)
+		_, err := cluster.Node(0).Group.Get(key)
+		c.So(err, c.ShouldBeNil)
+		c.So(cluster.Requests(0, owner.Index)-before, c.ShouldEqual, 1)
+		c.So(owner.Calls(key), c.ShouldEqual, 1)
+	})
+}
+
+func TestCompareAndSetIgnoresBoundedLoad(t *testing.T) {
+	cluster := NewCluster(3, 2<<10, source)
+	key := remoteKey(cluster, 0)
+	owner := cluster.Owner(key)
+	defer saturate(cluster, 0, owner, 4)()
+	cluster.SetBoundedLoad(0.25)
+
+	c.Convey("负责节点过载时 CompareAndSet 仍然在负责节点上执行", t, func() {
+		before := cluster.Requests(0, owner.Index)
+		version, err := cluster.Node(0).Group.CompareAndSet(key, 0, []byte("v1"))
+		c.So(err, c.ShouldBeNil)
+		c.So(cluster.Requests(0, owner.Index)-before, c.ShouldEqual, 1)
+
+		_, err = owner.Group.CompareAndSet(key, version, []byte("v2"))
+		c.So(err, c.ShouldBeNil)
+		for _, node := range cluster.Nodes {
+			if node != owner {
+				_, err := node.Group.CompareAndSet(key, version, []byte("v3"))
+				c.So(errors.Is(err, geecache.ErrVersionConflict), c.ShouldBeTrue)
+			}
+		}
+
+		// GetWithVersion 也从负责节点读取, 返回的版本可以直接用于 CompareAndSet
+		v, current, err := cluster.Node(0).Group.GetWithVersion(key)
+		c.So(err, c.ShouldBeNil)
+		c.So(v.String(), c.ShouldEqual, "v2")
+		_, err = cluster.Node(0).Group.CompareAndSet(key, current, []byte("v4"))
+		c.So(err, c.ShouldBeNil)
+	})
+}
+
+// saturate 让 n 个从 from 发往 owner 的请求保持进行中, 返回等待这些请求结束的函数
+func saturate(cluster *Cluster, from int, owner *Node, n int) func() {
+	cluster.SetLatency(from, owner.Index, 300*time.Millisecond)
+	var wg sync.WaitGroup
+	for i, sent := 0, 0; sent < n; i++ {
+		busy := "busy" + strconv.Itoa(i)
+		if cluster.Owner(busy) != owner {
+			continue
+		}
+		sent++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cluster.Node(from).Group.Get(busy)
+		}()
+	}
+	for cluster.Requests(from, owner.Index) < n {
+		time.Sleep(time.Millisecond)
+	}
+	return wg.Wait
+}
+
+func TestPutDelete(t *testing.T) {
+	cluster := NewCluster(3, 2<<10, source)
+	key := remoteKey(cluster, 0)
+	owner := cluster.Owner(key)
+
+	c.Convey("Put 和 Delete 在负责的节点上执行", t, func() {
+		c.So(cluster.Node(0).Group.Put(context.Background(), key, []byte("v1")), c.ShouldBeNil)
+		c.So(cluster.Requests(0, owner.Index), c.ShouldEqual, 1)
+		v, err := owner.Group.Get(key)
+		c.So(err, c.ShouldBeNil)
+		c.So(v.String(), c.ShouldEqual, "v1")
+		v, err = cluster.Node(0).Group.Get(key)
+		c.So(err, c.ShouldBeNil)
+		c.So(v.String(), c.ShouldEqual, "v1")
+
+		c.So(cluster.Node(0).Group.Delete(context.Background(), key), c.ShouldBeNil)
+		v, err = owner.Group.Get(key)
+		c.So(err, c.ShouldBeNil)
+		c.So(v.String(), c.ShouldEqual, "value:"+key)
+		c.So(owner.Calls(key), c.ShouldEqual, 1)
+	})
+}
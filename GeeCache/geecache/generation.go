@@ -0,0 +1,123 @@
+package geecache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+)
+
+const (
+	defaultFlushPath = "_flush/"
+	generationHeader = "X-Geecache-Generation"
+)
+
+// Generation 返回本组当前的代数, 代数小于它的缓存条目都视为已失效
+func (g *Group) Generation() uint64 {
+	return atomic.LoadUint64(&g.generation)
+}
+
+// Flush 将代数加一, 使本节点上已缓存的条目全部失效, 返回新的代数
+// 失效的条目在下次访问时才删除, 不会锁住整个缓存; 清空整个集群请使用 HTTPPool.Flush
+func (g *Group) Flush() uint64 {
+	return atomic.AddUint64(&g.generation, 1)
+}
+
+// SetGeneration 在 generation 大于当前代数时更新代数, 返回更新后的代数
+// 节点收到代数更大的请求时调用, 让错过 Flush 的节点追上集群
+func (g *Group) SetGeneration(generation uint64) uint64 {
+	for {
+		current := g.Generation()
+		if generation <= current {
+			return current
+		}
+		if atomic.CompareAndSwapUint64(&g.generation, current, generation) {
+			return generation
+		}
+	}
+}
+
+// flushTo 至少将代数加一, 且不小于 generation
+func (g *Group) flushTo(generation uint64) uint64 {
+	if next := g.Flush(); next >= generation {
+		return next
+	}
+	return g.SetGeneration(generation)
+}
+
+// stale 判断 value 是否属于更早的代数
+func (g *Group) stale(value ByteView) bool {
+	return value.generation < g.Generation()
+}
+
+// Flush 清空所有节点上名为 group 的 Group: 本节点代数加一后通知其他节点至少更新到同样的代数
+// 返回本节点新的代数和第一个通知失败的错误, 失败的节点会在收到代数更大的请求时追上
+func (p *HTTPPool) Flush(ctx context.Context, group string) (uint64, error) {
+	g := GetGroup(group)
+	if g == nil {
+		return 0, fmt.Errorf("no such group: %s", group)
+	}
+	generation := g.Flush()
+
+	p.mu.Lock()
+	getters := make(map[string]*httpGetter, len(p.httpGetter))
+	for peer, getter := range p.httpGetter {
+		if peer != p.self {
+			getters[peer] = getter
+		}
+	}
+	p.mu.Unlock()
+
+	var firstErr error
+	for peer, getter := range getters {
+		if err := getter.flush(ctx, group, generation); err != nil {
+			p.Log("flush %s on %s failed: %v", group, peer, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("flush %s on %s: %v", group, peer, err)
+			}
+		}
+	}
+	return generation, firstErr
+}
+
+// serveFlush 处理其他节点发来的 Flush, 响应中返回本节点新的代数
+func (p *HTTPPool) serveFlush(w http.ResponseWriter, r *http.Request, groupName string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	group := GetGroup(groupName)
+	if group == nil {
+		http.Error(w, "No such group: "+groupName, http.StatusNotFound)
+		return
+	}
+	generation, err := strconv.ParseUint(r.Header.Get(generationHeader), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid generation", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set(generationHeader, strconv.FormatUint(group.flushTo(generation), 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *httpGetter) flush(ctx context.Context, group string, generation uint64) error {
+	u := fmt.Sprintf("%v%v%v", h.baseURL, defaultFlushPath, url.QueryEscape(group))
+	req, err := http.NewRequest(http.MethodPost, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(generationHeader, strconv.FormatUint(generation, 10))
+
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("server returned: %v", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package geecache
+
+import (
+	"context"
+	pb "geecache/geecachepb"
+	"net/http/httptest"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFlush(t *testing.T) {
+	loads := 0
+	g := mustNewGroup(t, "generation", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte(key), nil
+	}))
+
+	c.Convey("Flush 之后的读取视为未命中", t, func() {
+		g.Get("Tom")
+		g.Get("Tom")
+		c.So(loads, c.ShouldEqual, 1)
+
+		c.So(g.Flush(), c.ShouldEqual, 1)
+		g.Get("Tom")
+		c.So(loads, c.ShouldEqual, 2)
+		c.So(g.mainCache.lru.Len(), c.ShouldEqual, 1)
+
+		// 更早代数的值对 CompareAndSet 来说不存在
+		g.Set("Jack", []byte("589"))
+		g.Flush()
+		_, err := g.CompareAndSet("Jack", 0, []byte("590"))
+		c.So(err, c.ShouldBeNil)
+
+		c.So(g.SetGeneration(1), c.ShouldEqual, 2)
+		c.So(g.SetGeneration(5), c.ShouldEqual, 5)
+		c.So(g.flushTo(3), c.ShouldEqual, 6)
+		c.So(g.flushTo(10), c.ShouldEqual, 10)
+	})
+}
+
+func TestHTTPGeneration(t *testing.T) {
+	g := mustNewGroup(t, "generation-http", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+	srv := httptest.NewServer(NewHTTPPool(""))
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + defaultBasePath}
+
+	c.Convey("节点之间同步代数", t, func() {
+		err := getter.Get(context.Background(), &pb.Request{Group: "generation-http", Key: "Tom", Generation: 5}, &pb.Response{})
+		c.So(err, c.ShouldBeNil)
+		c.So(g.Generation(), c.ShouldEqual, 5)
+
+		c.So(getter.flush(context.Background(), "generation-http", 3), c.ShouldBeNil)
+		c.So(g.Generation(), c.ShouldEqual, 6)
+		c.So(getter.flush(context.Background(), "no-such-group", 3), c.ShouldNotBeNil)
+	})
+}
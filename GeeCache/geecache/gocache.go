@@ -1,11 +1,16 @@
 package geecache
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	pb "geecache/geecachepb"
 	"geecache/singleflight"
+	"geecache/tracing"
+	"io"
 	"log"
 	"sync"
+	"time"
 )
 
 type Getter interface {
@@ -20,11 +25,27 @@ func (f GetterFunc) Get(key string) ([]byte, error) {
 }
 
 type Group struct {
-	name      string // 命名空间
-	getter    Getter // 未命中缓存时用来获取数据源的回调函数
-	mainCache cache  // 并发缓存
-	peers     PeerPicker
-	loader    *singleflight.Group
+	name        string // 命名空间
+	getter      Getter // 未命中缓存时用来获取数据源的回调函数
+	mainCache   cache  // 并发缓存
+	hotCache    cache  // 由其他节点负责, 但在本节点访问频繁的热点 key 的副本
+	hot         *hotKeys
+	peers       PeerPicker
+	loader      *singleflight.Group
+	localLoader *singleflight.Group // 其他节点发来的请求只在本节点加载
+	watchers    *watchHub           // 订阅本组变更事件的 Watcher
+	stats       groupStats
+	tracer      tracing.Tracer
+
+	chunkSize         int   // 超过该大小的值分块存储和传输, 0 表示不分块
+	maxValueSize      int64 // 允许缓存的最大值, 0 表示不限制
+	version           uint64
+	codec             Codec // 压缩编码, 为 nil 时不压缩
+	compressThreshold int   // 不小于该大小的值才压缩
+	filterMu          sync.RWMutex
+	keyFilter         *keyFilter // 合法 key 的布隆过滤器, 为 nil 时不过滤, 由 filterMu 保护
+	opts              GroupOptions
+	generation        uint64 // 当前代数, 更早代数的条目视为未命中
 }
 
 var (
@@ -32,22 +53,68 @@ var (
 	groups = make(map[string]*Group)
 )
 
-func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
+// LoadSource 表示值是从哪里加载的
+type LoadSource int
+
+const (
+	LoadFromPeer  LoadSource = iota // 从负责该 key 的远程节点获取
+	LoadFromLocal                   // 在本节点调用 Getter
+)
+
+func (s LoadSource) String() string {
+	if s == LoadFromPeer {
+		return "peer"
+	}
+	return "local"
+}
+
+// GroupOptions 是创建 Group 时的可选配置, 回调在请求路径上同步执行, 不应阻塞
+type GroupOptions struct {
+	// OnEvict 在条目因容量不足被淘汰时调用, 调用时持有缓存的锁, 不能再访问本 Group
+	OnEvict func(key string, value ByteView)
+	// OnLoad 在每次从远程节点或 Getter 加载之后调用, err 为加载的结果
+	OnLoad func(key string, source LoadSource, d time.Duration, err error)
+	// OnHit 在命中本节点的缓存 (包括热点副本) 时调用
+	OnHit func(key string)
+}
+
+// NewGroup 创建并注册名为 name 的 Group, 同名的 Group 已存在时返回 ErrGroupExists
+// opts 可以为 nil
+func NewGroup(name string, cacheBytes int64, getter Getter, opts *GroupOptions) (*Group, error) {
 	if getter == nil {
 		panic("nil Getter")
 	}
+	if opts == nil {
+		opts = &GroupOptions{}
+	}
 
 	mu.Lock()
 	defer mu.Unlock()
+	if _, ok := groups[name]; ok {
+		return nil, fmt.Errorf("%s: %w", name, ErrGroupExists)
+	}
 	g := &Group{
-		name:      name,
-		getter:    getter,
-		mainCache: cache{cacheBytes: cacheBytes},
-		loader:    &singleflight.Group{},
+		name:        name,
+		getter:      getter,
+		mainCache:   cache{cacheBytes: cacheBytes},
+		hotCache:    cache{cacheBytes: cacheBytes / 8},
+		loader:      &singleflight.Group{},
+		localLoader: &singleflight.Group{},
+		watchers:    &watchHub{group: name},
+		tracer:      tracing.Noop,
+		opts:        *opts,
+		// 以启动时间作为初始版本号, 节点重启后的版本号不会与重启前重复
+		version: uint64(time.Now().UnixNano()),
+	}
+	g.mainCache.onEvicted = func(key string, value ByteView) {
+		g.watchers.publish(EventExpire, key)
+		if g.opts.OnEvict != nil {
+			g.opts.OnEvict(key, value)
+		}
 	}
 
 	groups[name] = g
-	return g
+	return g, nil
 }
 
 func GetGroup(name string) *Group {
@@ -60,33 +127,171 @@ func GetGroup(name string) *Group {
 	return nil
 }
 
+// DestroyGroup 注销名为 name 的 Group, 停止后台任务并关闭所有 Watcher, 之后可以重新创建同名的 Group
+// 返回 Group 是否存在
+func DestroyGroup(name string) bool {
+	mu.Lock()
+	g, ok := groups[name]
+	delete(groups, name)
+	mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	g.DisableKeyFilter()
+	g.watchers.closeAll()
+	return true
+}
+
 func (g *Group) Get(key string) (ByteView, error) {
+	return g.GetContext(context.Background(), key)
+}
+
+// GetContext 与 Get 相同, ctx 用于传递追踪信息; ctx 结束时立即返回 ctx.Err(),
+// 已经开始的加载不会被取消, 继续在后台完成并写入缓存, 结果由其他等待的请求共享
+func (g *Group) GetContext(ctx context.Context, key string) (value ByteView, err error) {
+	return g.get(ctx, key, false)
+}
+
+// GetForPeer 处理其他节点发来的请求: 只查找本节点的缓存或调用 Getter, 不会再转发给其他节点
+// 负载有界的哈希会把 key 交给不负责它的节点, 再次转发会在节点之间来回传递
+// 节点间传输的服务端 (如 HTTPPool.ServeHTTP) 应当使用它而不是 GetContext
+func (g *Group) GetForPeer(ctx context.Context, key string) (ByteView, error) {
+	return g.get(ctx, key, true)
+}
+
+func (g *Group) get(ctx context.Context, key string, fromPeer bool) (value ByteView, err error) {
 	if key == "" {
 		return ByteView{}, fmt.Errorf("key is required")
 	}
 
-	// 命中主存
-	if v, ok := g.mainCache.get(key); ok {
-		log.Println("[GeeCache] hit")
+	ctx, span := g.tracer.Start(ctx, "geecache.Get")
+	span.SetTag("group", g.name)
+	span.SetTag("key", key)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	g.stats.add(&g.stats.gets)
+	if v, ok := g.lookupCache(ctx, key); ok {
 		return v, nil
 	}
 
+	if filter := g.filter(); filter != nil {
+		if !filter.has(key) {
+			g.stats.add(&g.stats.filterRejects)
+			span.SetTag("filter", "reject")
+			return ByteView{}, fmt.Errorf("%s: %w", key, ErrNotFound)
+		}
+		defer func() {
+			if errors.Is(err, ErrNotFound) {
+				g.stats.add(&g.stats.filterFalsePositives)
+			}
+		}()
+	}
+
+	if fromPeer {
+		span.SetTag("from_peer", true)
+		return g.loadLocally(ctx, key)
+	}
+	// 每次访问都在 singleflight 之前计数, 并发的未命中不会被合并成一次
+	if _, ok := g.pickOwner(key); ok {
+		g.recordHot(key)
+	}
 	// 未命中, 去其他节点获取
-	return g.load(key)
+	return g.load(ctx, key)
+}
+
+func (g *Group) hit(key string) {
+	if g.opts.OnHit != nil {
+		g.opts.OnHit(key)
+	}
+}
+
+func (g *Group) loaded(key string, source LoadSource, start time.Time, err error) {
+	if g.opts.OnLoad != nil {
+		g.opts.OnLoad(key, source, time.Since(start), err)
+	}
+}
+
+// lookupCache 依次查找主缓存和热点副本
+func (g *Group) lookupCache(ctx context.Context, key string) (ByteView, bool) {
+	_, span := g.tracer.Start(ctx, "cache.lookup")
+	defer span.End()
+
+	// 命中主存, 属于更早代数的条目视为未命中并顺便删除
+	if v, ok := g.mainCache.get(key); ok && g.stale(v) {
+		g.mainCache.removeOlder(key, g.Generation())
+	} else if ok {
+		log.Println("[GeeCache] hit")
+		g.stats.add(&g.stats.cacheHits)
+		g.mainCache.recordTenant(key, true)
+		g.hit(key)
+		span.SetTag("hit", "main")
+		return v, true
+	}
+
+	// 命中热点副本
+	if v, ok := g.hotCache.get(key); ok && g.stale(v) {
+		g.hotCache.removeOlder(key, g.Generation())
+	} else if ok && g.hot != nil && g.hot.expired(key, time.Now()) {
+		// 副本过期后从负责的节点重新加载, 避免一直返回旧值
+		g.hotCache.remove(key)
+	} else if ok {
+		g.stats.add(&g.stats.hotCacheHits)
+		g.mainCache.recordTenant(key, true)
+		g.hit(key)
+		g.recordHot(key)
+		span.SetTag("hit", "hot")
+		return v, true
+	}
+
+	g.mainCache.recordTenant(key, false)
+	span.SetTag("hit", "none")
+	return ByteView{}, false
 }
 
-func (g *Group) load(key string) (value ByteView, err error) {
-	viewi, err := g.loader.Do(key, func() (interface{}, error) {
+// peerTimeout 是共享的加载中一次远程请求的超时时间, 共享的加载不随调用方取消
+const peerTimeout = 10 * time.Second
+
+func (g *Group) load(ctx context.Context, key string) (value ByteView, err error) {
+	// 同一个 key 的并发请求只有一个会真正加载, 其余在这里等待
+	waitCtx, wait := g.tracer.Start(ctx, "singleflight.wait")
+	// 加载结果由所有等待的请求共享, 不随第一个请求取消; ctx 结束时只有当前请求停止等待
+	loadCtx := detach(waitCtx)
+	viewi, err, shared := g.loader.DoContext(ctx, key, func() (interface{}, error) {
 		if g.peers != nil {
 			if peer, ok := g.peers.PickPeer(key); ok {
-				if value, err := g.getFromPeer(peer, key); err == nil {
+				start := time.Now()
+				peerCtx, cancel := context.WithTimeout(loadCtx, peerTimeout)
+				value, err := g.getFromPeer(peerCtx, peer, key)
+				cancel()
+				g.loaded(key, LoadFromPeer, start, err)
+				if err == nil {
+					g.stats.add(&g.stats.peerLoads)
+					g.storeHot(key, value)
 					return value, nil
 				}
-				log.Println("[GeeCache] Failed to get from peer.", err)
+				if errors.Is(err, ErrNotFound) {
+					return nil, err
+				}
+				if errors.Is(err, ErrPeerOverloaded) {
+					g.stats.add(&g.stats.peerOverloads)
+				} else {
+					g.stats.add(&g.stats.peerErrors)
+					log.Println("[GeeCache] Failed to get from peer.", err)
+				}
 			}
 		}
-		return g.getlocally(key)
+		start := time.Now()
+		value, err := g.getlocally(loadCtx, key)
+		g.loaded(key, LoadFromLocal, start, err)
+		return value, err
 	})
+	wait.SetTag("leader", !shared)
+	wait.SetError(err)
+	wait.End()
 
 	if err == nil {
 		return viewi.(ByteView), nil
@@ -105,19 +310,153 @@ func (g *Group) load(key string) (value ByteView, err error) {
 	// return g.getlocally(key)
 }
 
-func (g *Group) getlocally(key string) (ByteView, error) {
+// loadLocally 只在本节点加载, 与 load 使用不同的 singleflight, 不会等待本节点发往其他节点的请求
+func (g *Group) loadLocally(ctx context.Context, key string) (ByteView, error) {
+	waitCtx, wait := g.tracer.Start(ctx, "singleflight.wait")
+	loadCtx := detach(waitCtx)
+	viewi, err, shared := g.localLoader.DoContext(ctx, key, func() (interface{}, error) {
+		start := time.Now()
+		value, err := g.getlocally(loadCtx, key)
+		g.loaded(key, LoadFromLocal, start, err)
+		return value, err
+	})
+	wait.SetTag("leader", !shared)
+	wait.SetError(err)
+	wait.End()
+	if err != nil {
+		return ByteView{}, err
+	}
+	return viewi.(ByteView), nil
+}
+
+func (g *Group) getlocally(ctx context.Context, key string) (value ByteView, err error) {
+	_, span := g.tracer.Start(ctx, "local.load")
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	// 加载期间发生 Flush 时按加载开始时的代数写入, 下次读取时视为未命中
+	generation := g.Generation()
 	bytes, err := g.getter.Get(key)
 	if err != nil {
+		g.stats.add(&g.stats.localLoadErrs)
 		return ByteView{}, err
 	}
+	if err := g.checkSize(int64(len(bytes))); err != nil {
+		g.stats.add(&g.stats.localLoadErrs)
+		return ByteView{}, err
+	}
+	g.stats.add(&g.stats.localLoads)
 
-	value := ByteView{b: cloneBytes(bytes)}
-	g.populateCache(key, value)
+	value = g.newView(bytes)
+	value.generation = generation
+	value = g.populateCache(key, value)
 	return value, nil
 }
 
-func (g *Group) populateCache(key string, value ByteView) {
-	g.mainCache.add(key, value)
+// populateCache 为 value 分配新的版本号后写入主缓存
+// 被准入策略拒绝时不通知订阅者, 返回的值没有版本号, 不能用于 CompareAndSet
+func (g *Group) populateCache(key string, value ByteView) ByteView {
+	value.version = g.nextVersion()
+	if !g.mainCache.add(key, value) {
+		value.version = 0
+		return value
+	}
+	g.watchers.publish(EventSet, key)
+	return value
+}
+
+// Set 直接将值写入本节点缓存
+func (g *Group) Set(key string, value []byte) error {
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	if err := g.checkSize(int64(len(value))); err != nil {
+		return err
+	}
+	g.populateCache(key, g.newView(value))
+	return nil
+}
+
+// Remove 使本节点缓存中的 key 失效
+func (g *Group) Remove(key string) {
+	g.hotCache.remove(key)
+	if g.mainCache.remove(key) {
+		g.watchers.publish(EventInvalidate, key)
+	}
+}
+
+// Watch 订阅本组的变更事件, keys 为精确匹配的 key, prefixes 为前缀, 均为空时订阅全部
+// 只能收到本节点缓存上发生的变更, 其他节点 (包括 key 的负责节点) 上的写入、失效和淘汰不会出现在这里
+func (g *Group) Watch(keys []string, prefixes []string) *Watcher {
+	return g.watchers.add(keys, prefixes)
+}
+
+// SetAdmissionPolicy 为主缓存设置准入策略, 例如 tinylfu.New(n)
+func (g *Group) SetAdmissionPolicy(policy AdmissionPolicy) {
+	g.mainCache.setAdmission(policy)
+}
+
+// EnableHotKeys 开启热点探测: 由其他节点负责的 key 在 window 内被访问 threshold 次后,
+// 在本节点的热点缓存中保留一份副本, 直到某个窗口内的访问次数低于 threshold
+// 副本保存 window 之后过期, 下一次访问会从负责的节点重新加载
+// 需要在开始服务之前调用
+func (g *Group) EnableHotKeys(threshold int64, window time.Duration) {
+	g.hot = newHotKeys(threshold, window)
+}
+
+// recordHot 记录一次对远程 key 的访问, 并降级冷却的热点
+func (g *Group) recordHot(key string) {
+	if g.hot == nil {
+		return
+	}
+	_, demoted := g.hot.record(key, time.Now())
+	g.demote(demoted)
+}
+
+// storeHot 在从其他节点取到值后调用, key 是热点时在本节点保存一份副本
+func (g *Group) storeHot(key string, value ByteView) {
+	if g.hot != nil && g.hot.store(key, time.Now()) {
+		g.hotCache.add(key, value)
+	}
+}
+
+func (g *Group) demote(keys []string) {
+	for _, key := range keys {
+		g.hotCache.remove(key)
+	}
+}
+
+// GetReader 以 io.Reader 的形式返回值, 适合读取大的值, 避免再拷贝一份
+func (g *Group) GetReader(key string) (io.Reader, error) {
+	view, err := g.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return view.Reader(), nil
+}
+
+// SetChunkSize 设置分块阈值, 超过 chunkSize 的值按块存储, 并以流的方式传给其他节点
+func (g *Group) SetChunkSize(chunkSize int) {
+	g.chunkSize = chunkSize
+}
+
+// SetMaxValueSize 设置允许缓存的最大值, 超过时 Get 和 Set 返回 ErrValueTooLarge
+func (g *Group) SetMaxValueSize(maxValueSize int64) {
+	g.maxValueSize = maxValueSize
+}
+
+func (g *Group) checkSize(n int64) error {
+	if g.maxValueSize > 0 && n > g.maxValueSize {
+		return fmt.Errorf("%d bytes: %w", n, ErrValueTooLarge)
+	}
+	return nil
+}
+
+// SetTracer 设置追踪器, 默认不记录
+func (g *Group) SetTracer(tracer tracing.Tracer) {
+	g.tracer = tracer
 }
 
 func (g *Group) RegisterPeers(peers PeerPicker) {
@@ -127,19 +466,50 @@ func (g *Group) RegisterPeers(peers PeerPicker) {
 	g.peers = peers
 }
 
-func (g *Group) getFromPeer(peer PeerGetter, key string) (ByteView, error) {
+func (g *Group) getFromPeer(ctx context.Context, peer PeerGetter, key string) (value ByteView, err error) {
+	ctx, span := g.tracer.Start(ctx, "peer.fetch")
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
 	// with protobuf
+	generation := g.Generation()
 	req := &pb.Request{
-		Group: g.name,
-		Key:   key,
+		Group:        g.name,
+		Key:          key,
+		AcceptCodecs: codecNames(),
+		Generation:   generation,
+		MaxValueSize: g.maxValueSize,
+		MaxChunkSize: int64(g.chunkSize),
 	}
 
 	res := &pb.Response{}
 
-	if err := peer.Get(req, res); err != nil {
+	if err := peer.Get(ctx, req, res); err != nil {
 		return ByteView{}, err
 	} else {
-		return ByteView{b: res.Value}, nil
+		value = ByteView{b: res.Value, version: res.Version, generation: generation}
+		if len(res.Chunks) > 0 {
+			value = ByteView{chunks: res.Chunks, version: res.Version, generation: generation}
+		}
+		if res.Codec != "" {
+			// 压缩的值原样保存, 读取时再解压
+			codec, ok := codecByName(res.Codec)
+			if !ok {
+				return ByteView{}, fmt.Errorf("unknown codec: %q", res.Codec)
+			}
+			value.codec = codec
+			value.n = int(res.RawSize)
+		}
+		// 不信任远程节点, 压缩前后的大小都不能超过限制
+		if err := g.checkSize(int64(value.size())); err != nil {
+			return ByteView{}, err
+		}
+		if err := g.checkSize(int64(value.Len())); err != nil {
+			return ByteView{}, err
+		}
+		return value, nil
 	}
 
 	// without protobuf
@@ -1,13 +1,26 @@
 package geecache
 
 import (
+	"context"
 	"fmt"
+	pb "geecache/geecachepb"
 	"log"
+	"sync"
 	"testing"
 
 	c "github.com/smartystreets/goconvey/convey"
 )
 
+// mustNewGroup 创建测试用的 Group, 测试结束时销毁, 同一个测试中的 Group 名字不能重复
+func mustNewGroup(t *testing.T, name string, cacheBytes int64, getter Getter) *Group {
+	g, err := NewGroup(name, cacheBytes, getter, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { DestroyGroup(name) })
+	return g
+}
+
 func TestGetter(t *testing.T) {
 	var f Getter
 	f = GetterFunc(func(key string) ([]byte, error) {
@@ -21,14 +34,14 @@ func TestGetter(t *testing.T) {
 	})
 }
 
-func initTestGroup() (*Group, *map[string]int) {
+func initTestGroup(t *testing.T) (*Group, *map[string]int) {
 	var db map[string]string = make(map[string]string)
 	db["Tom"] = "630"
 	db["Jack"] = "589"
 	db["Sam"] = "567"
 
 	var loadCounts map[string]int = make(map[string]int, len(db))
-	gee := NewGroup("scores", 2<<10, GetterFunc(
+	gee := mustNewGroup(t, "scores", 2<<10, GetterFunc(
 		func(key string) ([]byte, error) {
 			log.Println("[SlowDB] search key", key)
 			if v, ok := db[key]; ok {
@@ -43,7 +56,7 @@ func initTestGroup() (*Group, *map[string]int) {
 	return gee, &loadCounts
 }
 func TestGet(t *testing.T) {
-	g, lc := initTestGroup()
+	g, lc := initTestGroup(t)
 	c.Convey("Get 测试", t, func() {
 		tt := []struct {
 			name    string
@@ -67,3 +80,58 @@ func TestGet(t *testing.T) {
 		}
 	})
 }
+
+type ctxKey struct{}
+
+// ctxPeers 记录第一次远程请求收到的 context 的状态, 收到 release 之前不返回
+type ctxPeers struct {
+	once    sync.Once
+	record  sync.Once
+	started chan struct{}
+	release chan struct{}
+	err     error
+	value   interface{}
+}
+
+func (p *ctxPeers) PickPeer(key string) (PeerGetter, bool) {
+	return p, true
+}
+
+func (p *ctxPeers) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	p.once.Do(func() { close(p.started) })
+	<-p.release
+	p.record.Do(func() { p.err, p.value = ctx.Err(), ctx.Value(ctxKey{}) })
+	out.Value = []byte(in.GetKey())
+	return nil
+}
+
+func TestLoadDetached(t *testing.T) {
+	g := mustNewGroup(t, "detached", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+		}))
+	peers := &ctxPeers{started: make(chan struct{}), release: make(chan struct{})}
+	g.RegisterPeers(peers)
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "trace"))
+	first := make(chan error, 1)
+	go func() {
+		_, err := g.GetContext(ctx, "Tom")
+		first <- err
+	}()
+	<-peers.started
+	second := make(chan ByteView, 1)
+	go func() {
+		v, _ := g.Get("Tom")
+		second <- v
+	}()
+
+	c.Convey("取消的请求立即返回, 共享的加载继续进行并保留其中的值", t, func() {
+		cancel()
+		c.So(<-first, c.ShouldEqual, context.Canceled)
+		close(peers.release)
+		c.So((<-second).String(), c.ShouldEqual, "Tom")
+		c.So(peers.err, c.ShouldBeNil)
+		c.So(peers.value, c.ShouldEqual, "trace")
+	})
+}
@@ -0,0 +1,187 @@
+package geecache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	consistenthash "geecache/consistenhash"
+	pb "geecache/geecachepb"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultHandoffPath     = "_handoff/"
+	defaultHandoffBatch    = 100                    // 每批移交的条目数
+	defaultHandoffInterval = 100 * time.Millisecond // 两批之间的最小间隔, 用于限速
+	maxHandoffBody         = 64 << 20               // 接收一批移交的请求体上限
+)
+
+// SetHandoffRate 设置节点变更后移交 key 的批大小和每秒最多发送的批数, 两者都必须大于 0
+func (p *HTTPPool) SetHandoffRate(batchSize int, batchesPerSecond int) error {
+	if batchSize <= 0 || batchesPerSecond <= 0 {
+		return fmt.Errorf("invalid handoff rate: batch size %d, %d batches per second", batchSize, batchesPerSecond)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handoffBatch = batchSize
+	p.handoffInterval = time.Second / time.Duration(batchesPerSecond)
+	return nil
+}
+
+// rebalance 找出本节点缓存中负责节点已经变化的 key, 分批移交给新的负责节点
+// 移交成功的 key 会从本节点删除
+func (p *HTTPPool) rebalance(ctx context.Context, ring *consistenthash.Map, getters map[string]*httpGetter, batchSize int, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for _, g := range groupsOf(p) {
+		// 遍历时持有缓存的锁, 只记录 key, 值在发送每一批之前再读取
+		pending := make(map[string][]string)
+		g.mainCache.rangeEntries(func(key string, value ByteView) bool {
+			if g.stale(value) {
+				return true
+			}
+			if owner := ring.Get(key); owner != "" && owner != p.self {
+				pending[owner] = append(pending[owner], key)
+			}
+			return true
+		})
+
+		for owner, keys := range pending {
+			for len(keys) > 0 {
+				n := batchSize
+				if n > len(keys) {
+					n = len(keys)
+				}
+				batch := keys[:n]
+				keys = keys[n:]
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+
+				entries := g.handoffEntries(batch)
+				if len(entries) == 0 {
+					continue
+				}
+				req := &pb.HandoffRequest{Group: g.name, Entries: entries}
+				if err := getters[owner].handoff(ctx, req); err != nil {
+					p.Log("handoff %d keys of %s to %s failed: %v", len(entries), g.name, owner, err)
+					continue
+				}
+				for _, e := range entries {
+					g.mainCache.remove(e.GetKey())
+				}
+				p.Log("handoff %d keys of %s to %s", len(entries), g.name, owner)
+			}
+		}
+	}
+}
+
+// handoffEntries 读取一批要移交的 key 的值, 已经不在缓存中或无法解压的 key 被跳过
+func (g *Group) handoffEntries(keys []string) []*pb.Entry {
+	entries := make([]*pb.Entry, 0, len(keys))
+	for _, key := range keys {
+		value, ok := g.mainCache.peek(key)
+		if !ok || g.stale(value) {
+			continue
+		}
+		b, err := value.Bytes()
+		if err != nil {
+			// 无法解压的值不移交, 留给负责节点重新加载
+			log.Println("[GeeCache] Failed to decode value for handoff.", err)
+			continue
+		}
+		entries = append(entries, &pb.Entry{Key: key, Value: b, Version: value.Version()})
+	}
+	return entries
+}
+
+// serveHandoff 接收其他节点移交过来的 key
+func (p *HTTPPool) serveHandoff(w http.ResponseWriter, r *http.Request, groupName string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	group := GetGroup(groupName)
+	if group == nil {
+		http.Error(w, "No such group: "+groupName, http.StatusNotFound)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxHandoffBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	req := &pb.HandoffRequest{}
+	if err := proto.Unmarshal(body, req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, e := range req.GetEntries() {
+		group.acceptHandoff(e)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// acceptHandoff 只在 key 不在本节点缓存中时写入, 避免覆盖节点变更之后加载或写入的新值
+// 移交的值保留原来的版本号, 之后在本节点分配的版本号都大于它
+func (g *Group) acceptHandoff(e *pb.Entry) {
+	view := g.newView(e.GetValue())
+	view.version = e.GetVersion()
+	if view.version == 0 {
+		view.version = g.nextVersion()
+	}
+	g.observeVersion(view.version)
+	if _, ok := g.mainCache.compareAndSet(e.GetKey(), 0, view); ok {
+		g.watchers.publish(EventSet, e.GetKey())
+	}
+}
+
+func (h *httpGetter) handoff(ctx context.Context, in *pb.HandoffRequest) error {
+	body, err := proto.Marshal(in)
+	if err != nil {
+		return err
+	}
+	u := fmt.Sprintf("%v%v%v", h.baseURL, defaultHandoffPath, url.QueryEscape(in.GetGroup()))
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("server returned: %v", res.Status)
+	}
+	return nil
+}
+
+// groupsOf 返回使用 peers 作为节点选择器的 Group
+func groupsOf(peers PeerPicker) []*Group {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var gs []*Group
+	for _, g := range groups {
+		if g.peers == peers {
+			gs = append(gs, g)
+		}
+	}
+	return gs
+}
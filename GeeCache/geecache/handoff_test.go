@@ -0,0 +1,148 @@
+package geecache
+
+import (
+	"bytes"
+	consistenthash "geecache/consistenhash"
+	pb "geecache/geecachepb"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHandoff(t *testing.T) {
+	var (
+		lock     sync.Mutex
+		received = make(map[string]string)
+		batches  int
+	)
+	// 新加入的节点, 只记录收到的移交请求
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		req := &pb.HandoffRequest{}
+		proto.Unmarshal(body, req)
+
+		lock.Lock()
+		defer lock.Unlock()
+		batches++
+		for _, e := range req.GetEntries() {
+			received[e.GetKey()] = string(e.GetValue())
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer peer.Close()
+
+	self := "http://self"
+	g := mustNewGroup(t, "handoff", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+	pool := NewHTTPPool(self)
+	pool.Set(self)
+	if err := pool.SetHandoffRate(2, 1000); err != nil {
+		t.Fatal(err)
+	}
+	g.RegisterPeers(pool)
+	for i := 0; i < 20; i++ {
+		g.Get(strconv.Itoa(i))
+	}
+
+	pool.Set(self, peer.URL)
+
+	ring := consistenthash.New(defaultReplicas, nil)
+	ring.Add(self, peer.URL)
+	moved := make(map[string]string)
+	for i := 0; i < 20; i++ {
+		key := strconv.Itoa(i)
+		if ring.Get(key) == peer.URL {
+			moved[key] = key
+		}
+	}
+
+	cached := func() (n int) {
+		for key := range moved {
+			if _, ok := g.mainCache.get(key); ok {
+				n++
+			}
+		}
+		return
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for cached() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	c.Convey("节点变更后移交 key", t, func() {
+		lock.Lock()
+		defer lock.Unlock()
+		c.So(moved, c.ShouldNotBeEmpty)
+		c.So(received, c.ShouldResemble, moved)
+		c.So(batches, c.ShouldEqual, (len(moved)+1)/2)
+		c.So(cached(), c.ShouldEqual, 0)
+		c.So(g.mainCache.lru.Len(), c.ShouldEqual, 20-len(moved))
+	})
+}
+
+func TestHandoffEntries(t *testing.T) {
+	g := mustNewGroup(t, "handoff-entries", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+	g.Get("a")
+	g.Get("b")
+
+	c.Convey("值在发送前读取, 已经删除的 key 被跳过", t, func() {
+		g.Remove("b")
+		entries := g.handoffEntries([]string{"a", "b", "c"})
+		c.So(len(entries), c.ShouldEqual, 1)
+		c.So(entries[0].GetKey(), c.ShouldEqual, "a")
+		c.So(string(entries[0].GetValue()), c.ShouldEqual, "a")
+	})
+}
+
+func TestServeHandoff(t *testing.T) {
+	g := mustNewGroup(t, "handoff-serve", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+	pool := NewHTTPPool("http://self")
+
+	c.Convey("只写入不存在的 key, 并保留原来的版本号", t, func() {
+		c.So(g.Set("a", []byte("new")), c.ShouldBeNil)
+		body, _ := proto.Marshal(&pb.HandoffRequest{Group: "handoff-serve", Entries: []*pb.Entry{
+			{Key: "a", Value: []byte("old"), Version: 100},
+			{Key: "b", Value: []byte("moved"), Version: 50},
+		}})
+		r := httptest.NewRequest(http.MethodPost, defaultBasePath+defaultHandoffPath+"handoff-serve", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		pool.ServeHTTP(w, r)
+		c.So(w.Code, c.ShouldEqual, http.StatusNoContent)
+
+		v, _, _ := g.GetWithVersion("a")
+		c.So(v.String(), c.ShouldEqual, "new")
+		v, version, _ := g.GetWithVersion("b")
+		c.So(v.String(), c.ShouldEqual, "moved")
+		c.So(version, c.ShouldEqual, 50)
+
+		next, err := g.CompareAndSet("b", 50, []byte("v2"))
+		c.So(err, c.ShouldBeNil)
+		c.So(next, c.ShouldBeGreaterThan, 100)
+	})
+}
+
+func TestSetHandoffRate(t *testing.T) {
+	pool := NewHTTPPool("http://self")
+	c.Convey("移交的批大小和速率必须大于 0", t, func() {
+		c.So(pool.SetHandoffRate(0, 10), c.ShouldNotBeNil)
+		c.So(pool.SetHandoffRate(10, 0), c.ShouldNotBeNil)
+		c.So(pool.SetHandoffRate(-1, 10), c.ShouldNotBeNil)
+		c.So(pool.SetHandoffRate(10, 10), c.ShouldBeNil)
+		c.So(pool.handoffInterval, c.ShouldEqual, 100*time.Millisecond)
+	})
+}
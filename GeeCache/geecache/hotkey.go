@@ -0,0 +1,100 @@
+package geecache
+
+import (
+	"geecache/tinylfu"
+	"sort"
+	"sync"
+	"time"
+)
+
+// hotKeyCounters 是统计访问次数的 Count-Min Sketch 每一行的计数器个数
+const hotKeyCounters = 1 << 12
+
+// hotKeys 统计由其他节点负责的 key 的访问频率
+// 一个窗口内访问次数达到 threshold 的 key 被提升为热点, 在每个节点本地缓存一份副本
+// 窗口结束时访问次数低于 threshold 的热点被降级, 副本保存一个窗口后过期, 从负责的节点重新加载
+// 访问次数由固定大小的 Count-Min Sketch 估计, 内存不随 key 的数量增长, 冲突只会让 key 更容易成为热点
+type hotKeys struct {
+	mu        sync.Mutex
+	threshold int64
+	window    time.Duration
+	start     time.Time            // 当前窗口的起始时间
+	counts    *tinylfu.CountMin    // 当前窗口内的访问次数
+	hot       map[string]struct{}  // 热点集合
+	expires   map[string]time.Time // 热点副本的过期时间
+}
+
+func newHotKeys(threshold int64, window time.Duration) *hotKeys {
+	return &hotKeys{
+		threshold: threshold,
+		window:    window,
+		start:     time.Now(),
+		counts:    tinylfu.NewCountMin(hotKeyCounters),
+		hot:       make(map[string]struct{}),
+		expires:   make(map[string]time.Time),
+	}
+}
+
+// record 记录一次访问, 返回 key 当前是否为热点以及因窗口结束而被降级的 key
+func (h *hotKeys) record(key string, now time.Time) (hot bool, demoted []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	demoted = h.rotate(now)
+	if int64(h.counts.Add(key)) >= h.threshold {
+		h.hot[key] = struct{}{}
+	}
+	_, hot = h.hot[key]
+	return
+}
+
+// store 在保存热点副本之前调用, key 是热点时记录副本的过期时间并返回 true
+func (h *hotKeys) store(key string, now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.hot[key]; !ok {
+		return false
+	}
+	h.expires[key] = now.Add(h.window)
+	return true
+}
+
+// expired 返回 key 的热点副本是否已经过期
+func (h *hotKeys) expired(key string, now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	expires, ok := h.expires[key]
+	return !ok || !now.Before(expires)
+}
+
+// rotate 在窗口结束时降级冷却的热点并开始新的窗口
+func (h *hotKeys) rotate(now time.Time) (demoted []string) {
+	if now.Sub(h.start) < h.window {
+		return nil
+	}
+	for key := range h.hot {
+		if int64(h.counts.Estimate(key)) < h.threshold {
+			delete(h.hot, key)
+			delete(h.expires, key)
+			demoted = append(demoted, key)
+		}
+	}
+	h.counts.Reset()
+	h.start = now
+	return
+}
+
+// list 返回排序后的热点列表以及因窗口结束而被降级的 key
+func (h *hotKeys) list(now time.Time) (keys []string, demoted []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	demoted = h.rotate(now)
+	for key := range h.hot {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
@@ -0,0 +1,122 @@
+package geecache
+
+import (
+	"context"
+	pb "geecache/geecachepb"
+	"sync"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+// remotePeers 把所有 key 都交给同一个远程节点
+type remotePeers struct {
+	calls int
+	delay time.Duration
+}
+
+func (p *remotePeers) PickPeer(key string) (PeerGetter, bool) {
+	return p, true
+}
+
+func (p *remotePeers) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	p.calls++
+	time.Sleep(p.delay)
+	out.Value = []byte("remote:" + in.GetKey())
+	return nil
+}
+
+func TestHotKeys(t *testing.T) {
+	g := mustNewGroup(t, "hot", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+	peers := &remotePeers{}
+	g.RegisterPeers(peers)
+	g.EnableHotKeys(3, time.Hour)
+
+	for i := 0; i < 5; i++ {
+		g.Get("celebrity")
+	}
+	g.Get("nobody")
+
+	c.Convey("热点 key 被复制到本节点", t, func() {
+		c.So(peers.calls, c.ShouldEqual, 4)
+		v, err := g.Get("celebrity")
+		c.So(err, c.ShouldBeNil)
+		c.So(v.String(), c.ShouldEqual, "remote:celebrity")
+
+		stats := g.Stats()
+		c.So(stats.HotKeys, c.ShouldResemble, []string{"celebrity"})
+		c.So(stats.HotCacheHits, c.ShouldEqual, 3)
+		c.So(stats.PeerLoads, c.ShouldEqual, 4)
+	})
+}
+
+func TestHotKeysDemote(t *testing.T) {
+	h := newHotKeys(2, time.Minute)
+	now := h.start
+
+	c.Convey("冷却后降级", t, func() {
+		h.record("k", now)
+		hot, _ := h.record("k", now)
+		c.So(hot, c.ShouldBeTrue)
+
+		// 下一个窗口内仍然频繁访问, 保持热点
+		now = now.Add(time.Minute)
+		h.record("k", now)
+		hot, demoted := h.record("k", now)
+		c.So(hot, c.ShouldBeTrue)
+		c.So(demoted, c.ShouldBeEmpty)
+
+		// 窗口内访问不足, 降级
+		now = now.Add(time.Minute)
+		hot, demoted = h.record("k", now)
+		c.So(hot, c.ShouldBeTrue)
+		c.So(demoted, c.ShouldBeEmpty)
+		keys, demoted := h.list(now.Add(time.Minute))
+		c.So(keys, c.ShouldBeEmpty)
+		c.So(demoted, c.ShouldResemble, []string{"k"})
+	})
+}
+
+func TestHotKeysBurst(t *testing.T) {
+	g := mustNewGroup(t, "hot-burst", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+	peers := &remotePeers{delay: 50 * time.Millisecond}
+	g.RegisterPeers(peers)
+	g.EnableHotKeys(3, time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.Get("celebrity")
+		}()
+	}
+	wg.Wait()
+
+	c.Convey("并发的未命中逐次计数", t, func() {
+		c.So(peers.calls, c.ShouldEqual, 1)
+		c.So(g.Stats().HotKeys, c.ShouldResemble, []string{"celebrity"})
+		g.Get("celebrity")
+		c.So(peers.calls, c.ShouldEqual, 1)
+	})
+}
+
+func TestHotKeysExpire(t *testing.T) {
+	h := newHotKeys(1, time.Minute)
+	now := h.start
+
+	c.Convey("热点副本一个窗口后过期", t, func() {
+		c.So(h.store("k", now), c.ShouldBeFalse)
+		h.record("k", now)
+		c.So(h.store("k", now), c.ShouldBeTrue)
+		c.So(h.expired("k", now.Add(time.Second)), c.ShouldBeFalse)
+		c.So(h.expired("k", now.Add(time.Minute)), c.ShouldBeTrue)
+	})
+}
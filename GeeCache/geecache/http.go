@@ -1,22 +1,45 @@
 package geecache
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	consistenthash "geecache/consistenhash"
 	pb "geecache/geecachepb"
+	"geecache/tracing"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"google.golang.org/protobuf/proto"
 )
 
 const (
-	defaultBasePath = "/_geecache/"
-	defaultReplicas = 50
+	defaultBasePath  = "/_geecache/"
+	defaultReplicas  = 50
+	defaultWatchPath = "_watch/"
+	notFoundHeader   = "X-Geecache-Not-Found"
+
+	// 分块传输大值时使用的响应类型, chunkSizeHeader 为每块的大小
+	streamContentType = "application/x-geecache-stream"
+	chunkSizeHeader   = "X-Geecache-Chunk-Size"
+	versionHeader     = "X-Geecache-Version"
+	codecHeader       = "X-Geecache-Codec"    // 分块传输压缩后的数据时使用的编码
+	rawSizeHeader     = "X-Geecache-Raw-Size" // 分块传输压缩后的数据时压缩前的大小
+
+	// maxResponseOverhead 是整体编码的响应中值以外的字段 (版本号, 编码等) 最多占用的字节数
+	maxResponseOverhead = 1 << 10
+
+	// fromPeerHeader 标记节点之间的请求, 收到的节点只在本地加载, 不再转发
+	fromPeerHeader = "X-Geecache-From-Peer"
 )
 
 type HTTPPool struct {
@@ -25,15 +48,34 @@ type HTTPPool struct {
 	mu         sync.Mutex
 	peers      *consistenthash.Map    // 根据 key 选择合适的 peer
 	httpGetter map[string]*httpGetter // 远程节点和 httpGetter 的映射表
+
+	handoffBatch    int                // 节点变更后每批移交的条目数
+	handoffInterval time.Duration      // 两批移交之间的最小间隔
+	cancelHandoff   context.CancelFunc // 取消正在进行的移交
+
+	epsilon float64  // 大于 0 时启用负载有界的一致性哈希
+	limiter *limiter // 为 nil 时不限制并发
+
+	closeWatches     chan struct{} // 关闭后所有 _watch 长连接结束
+	closeWatchesOnce sync.Once
 }
 
 func NewHTTPPool(self string) *HTTPPool {
 	return &HTTPPool{
-		self:     self,
-		basePath: defaultBasePath,
+		self:            self,
+		basePath:        defaultBasePath,
+		handoffBatch:    defaultHandoffBatch,
+		handoffInterval: defaultHandoffInterval,
+		closeWatches:    make(chan struct{}),
 	}
 }
 
+// CloseWatches 结束所有 _watch 长连接, 可以通过 http.Server.RegisterOnShutdown 注册,
+// 否则 Shutdown 会一直等待这些连接直到超时
+func (p *HTTPPool) CloseWatches() {
+	p.closeWatchesOnce.Do(func() { close(p.closeWatches) })
+}
+
 func (p *HTTPPool) Log(format string, v ...interface{}) {
 	log.Printf("[Server %s] %s", p.self, fmt.Sprintf(format, v...))
 }
@@ -45,9 +87,37 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	p.Log("%s %s", r.Method, r.URL.Path)
 
+	path := r.URL.Path[len(p.basePath):]
+	switch {
+	// /<basepath>/_watch/<groupname>?key=<key>&prefix=<prefix>
+	case strings.HasPrefix(path, defaultWatchPath):
+		p.serveWatch(w, r, path[len(defaultWatchPath):])
+		return
+	// /<basepath>/_handoff/<groupname>
+	case strings.HasPrefix(path, defaultHandoffPath):
+		if !p.acquire(w, r) {
+			return
+		}
+		defer p.release()
+		p.serveHandoff(w, r, path[len(defaultHandoffPath):])
+		return
+	// /<basepath>/_cas/<groupname>
+	case strings.HasPrefix(path, defaultCASPath):
+		if !p.acquire(w, r) {
+			return
+		}
+		defer p.release()
+		p.serveCompareAndSet(w, r, path[len(defaultCASPath):])
+		return
+	// /<basepath>/_flush/<groupname>
+	case strings.HasPrefix(path, defaultFlushPath):
+		p.serveFlush(w, r, path[len(defaultFlushPath):])
+		return
+	}
+
 	// /<basepath>/<groupname>/<key>
 	// parts = [<groupname>, <key>]
-	parts := strings.SplitN(r.URL.Path[len(p.basePath):], "/", 2)
+	parts := strings.SplitN(path, "/", 2)
 	if len(parts) != 2 {
 		http.Error(w, "Bad Request", http.StatusBadRequest)
 		return
@@ -62,14 +132,78 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	view, err := group.Get(key)
+	if !p.acquire(w, r) {
+		return
+	}
+	defer p.release()
+
+	if r.Method == http.MethodPut || r.Method == http.MethodDelete {
+		p.serveWrite(w, r, group, key)
+		return
+	}
+
+	// 请求方的代数更大说明本节点错过了 Flush
+	if generation, err := strconv.ParseUint(r.Header.Get(generationHeader), 10, 64); err == nil {
+		group.SetGeneration(generation)
+	}
+
+	ctx := tracing.Extract(r.Context(), r.Header)
+	var view ByteView
+	var err error
+	if r.Header.Get(fromPeerHeader) != "" {
+		view, err = group.GetForPeer(ctx, key)
+	} else {
+		view, err = group.GetContext(ctx, key)
+	}
+	if errors.Is(err, ErrNotFound) {
+		// 与 group 不存在的 404 区分开
+		w.Header().Set(notFoundHeader, "1")
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	// 请求方支持时直接发送压缩后的数据, 不在本节点解压
+	compressed := view.codec != nil && acceptsCodec(strings.Split(r.Header.Get(acceptCodecHeader), ","), view.codec.Name())
+
+	// 分块存储的大值直接以流的方式写入响应体, 不再整体编码
+	if view.chunked() {
+		reader, size := view.Reader(), view.Len()
+		if compressed {
+			reader, size = view.rawReader(), view.size()
+			w.Header().Set(codecHeader, view.codec.Name())
+			w.Header().Set(rawSizeHeader, strconv.Itoa(view.Len()))
+		}
+		w.Header().Set("Content-Type", streamContentType)
+		w.Header().Set(chunkSizeHeader, strconv.Itoa(len(view.chunks[0])))
+		w.Header().Set(versionHeader, strconv.FormatUint(view.Version(), 10))
+		w.Header().Set("Content-Length", strconv.Itoa(size))
+		io.Copy(w, reader)
+		return
+	}
+
+	if compressed {
+		res := &pb.Response{Value: view.b, Version: view.Version(), Codec: view.codec.Name(), RawSize: int64(view.Len())}
+		body, err := proto.Marshal(res)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(body)
+		return
+	}
+
 	// 将值写入到响应体中
-	body, err := proto.Marshal(&pb.Response{Value: view.ByteSlice()})
+	value, err := view.Bytes()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body, err := proto.Marshal(&pb.Response{Value: value, Version: view.Version()})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -79,10 +213,54 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Write(body)
 }
 
+// serveWatch 以长连接的方式持续推送变更事件, 每行一个 JSON 编码的 Event
+// 只推送本节点上的事件, 不会转发给其他节点, 需要整个集群的事件时客户端要分别订阅每个节点
+func (p *HTTPPool) serveWatch(w http.ResponseWriter, r *http.Request, groupName string) {
+	group := GetGroup(groupName)
+	if group == nil {
+		http.Error(w, "No such group: "+groupName, http.StatusNotFound)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	watcher := group.Watch(query["key"], query["prefix"])
+	defer watcher.Close()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-p.closeWatches:
+			return
+		case event, ok := <-watcher.Events():
+			if !ok {
+				return
+			}
+			if err := enc.Encode(event); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// Set 更新节点列表, 如果是节点变更 (而非首次设置), 会在后台把不再属于本节点的 key 移交给新的负责节点
 func (p *HTTPPool) Set(peers ...string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	changed := p.peers != nil
 	p.peers = consistenthash.New(defaultReplicas, nil)
 	p.peers.Add(peers...)
 
@@ -90,45 +268,133 @@ func (p *HTTPPool) Set(peers ...string) {
 	for _, peer := range peers {
 		p.httpGetter[peer] = &httpGetter{baseURL: peer + p.basePath}
 	}
+
+	if !changed {
+		return
+	}
+	if p.cancelHandoff != nil {
+		p.cancelHandoff()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancelHandoff = cancel
+	go p.rebalance(ctx, p.peers, p.httpGetter, p.handoffBatch, p.handoffInterval)
+}
+
+// SetBoundedLoad 启用负载有界的一致性哈希, 本节点发往某个节点且进行中的请求数超过 (1+epsilon) 倍平均值时跳过该节点
+// 被选中的节点只在本地加载, 不会再转发给负责 key 的节点
+// epsilon 为 0 时关闭
+func (p *HTTPPool) SetBoundedLoad(epsilon float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.epsilon = epsilon
+}
+
+// load 返回本节点发往 peer 且还未完成的请求数, 调用时需持有 p.mu
+// 只统计发出的请求, 本节点自身不计入平均负载
+func (p *HTTPPool) load(peer string) int64 {
+	if getter, ok := p.httpGetter[peer]; ok {
+		return atomic.LoadInt64(&getter.inflight)
+	}
+	return 0
 }
 
 func (p *HTTPPool) PickPeer(key string) (PeerGetter, bool) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if peer := p.peers.Get(key); peer != "" && peer != p.self {
+	var peer string
+	if p.epsilon > 0 {
+		peer = p.peers.GetBounded(key, p.load, p.epsilon, p.self)
+	} else {
+		peer = p.peers.Get(key)
+	}
+	if peer != "" && peer != p.self {
 		p.Log("Pick peer %s", peer)
 		return p.httpGetter[peer], true
 	}
 	return nil, false
 }
 
+// PickOwner 返回哈希环上负责 key 的节点, 不受负载有界的影响
+func (p *HTTPPool) PickOwner(key string) (PeerGetter, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if peer := p.peers.Get(key); peer != "" && peer != p.self {
+		p.Log("Pick owner %s", peer)
+		return p.httpGetter[peer], true
+	}
+	return nil, false
+}
+
 type httpGetter struct {
-	baseURL string // 要访问的远程节点地址
+	baseURL  string // 要访问的远程节点地址
+	inflight int64  // 正在进行的请求数
+	retryAt  int64  // 远程节点过载时, 在该时间 (UnixNano) 之前不再发送请求
 }
 
-func (h *httpGetter) Get(in *pb.Request, out *pb.Response) error {
+func (h *httpGetter) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	if time.Now().UnixNano() < atomic.LoadInt64(&h.retryAt) {
+		return fmt.Errorf("%s: %w", in.GetKey(), ErrPeerOverloaded)
+	}
+	atomic.AddInt64(&h.inflight, 1)
+	defer atomic.AddInt64(&h.inflight, -1)
+
 	// func (h *httpGetter) Get(group string, key string) ([]byte, error) {
 	// u := fmt.Sprintf("%v%v/%v", h.baseURL, url.QueryEscape(group), url.QueryEscape(key))
 	u := fmt.Sprintf("%v%v/%v", h.baseURL, url.QueryEscape(in.GetGroup()), url.QueryEscape(in.GetKey()))
 
-	res, err := http.Get(u)
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	tracing.Inject(ctx, req.Header)
+	req.Header.Set(fromPeerHeader, "1")
+	if len(in.GetAcceptCodecs()) > 0 {
+		req.Header.Set(acceptCodecHeader, strings.Join(in.GetAcceptCodecs(), ","))
+	}
+	if in.GetGeneration() > 0 {
+		req.Header.Set(generationHeader, strconv.FormatUint(in.GetGeneration(), 10))
+	}
+
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		// return nil, err
 		return err
 	}
 	defer res.Body.Close()
 
+	if res.StatusCode == http.StatusNotFound && res.Header.Get(notFoundHeader) != "" {
+		return fmt.Errorf("%s: %w", in.GetKey(), ErrNotFound)
+	}
+	if res.StatusCode == http.StatusServiceUnavailable {
+		h.backoff(res.Header.Get("Retry-After"))
+		return fmt.Errorf("%s: %w", in.GetKey(), ErrPeerOverloaded)
+	}
 	if res.StatusCode != http.StatusOK {
 		// return nil, fmt.Errorf("server returned: %v", res.Status)
 		return fmt.Errorf("server returned: %v", res.Status)
 	}
 
-	bytes, err := ioutil.ReadAll(res.Body)
+	if res.Header.Get("Content-Type") == streamContentType {
+		return readChunks(res, out, in.GetMaxChunkSize(), in.GetMaxValueSize())
+	}
+
+	// 整体编码的响应除了值以外只有少量字段, 最多多读 1 字节, 用于发现超过 MaxValueSize 的值
+	body := io.Reader(res.Body)
+	limit := in.GetMaxValueSize()
+	if limit > 0 {
+		limit += maxResponseOverhead
+		body = io.LimitReader(res.Body, limit+1)
+	}
+	bytes, err := ioutil.ReadAll(body)
 	if err != nil {
 		// return nil, fmt.Errorf("reading response body: %v", err)
 		return fmt.Errorf("reading response body: %v", err)
 	}
+	if limit > 0 && int64(len(bytes)) > limit {
+		return fmt.Errorf("%s: response exceeds %d bytes: %w", in.GetKey(), limit, ErrValueTooLarge)
+	}
 
 	if err = proto.Unmarshal(bytes, out); err != nil {
 		return fmt.Errorf("decoding response body: %v", err)
@@ -137,4 +403,108 @@ func (h *httpGetter) Get(in *pb.Request, out *pb.Response) error {
 	return nil
 }
 
+// backoff 在 Retry-After 指定的秒数内不再向该节点发送请求, 默认 1 秒
+func (h *httpGetter) backoff(retryAfter string) {
+	seconds, err := strconv.Atoi(retryAfter)
+	if err != nil || seconds <= 0 {
+		seconds = 1
+	}
+	atomic.StoreInt64(&h.retryAt, time.Now().Add(time.Duration(seconds)*time.Second).UnixNano())
+}
+
+// readChunks 按服务端的分块大小逐块读取响应体, 避免一次读入整个值
+// 分块大小超过请求方的 maxChunk, 或者总大小超过 maxSize 时返回 ErrValueTooLarge, 两者为 0 时不限制
+func readChunks(res *http.Response, out *pb.Response, maxChunk, maxSize int64) error {
+	chunkSize, err := strconv.ParseInt(res.Header.Get(chunkSizeHeader), 10, 64)
+	if err != nil || chunkSize <= 0 {
+		return fmt.Errorf("invalid chunk size: %q", res.Header.Get(chunkSizeHeader))
+	}
+	if maxChunk > 0 && chunkSize > maxChunk {
+		return fmt.Errorf("chunk size %d exceeds %d: %w", chunkSize, maxChunk, ErrValueTooLarge)
+	}
+	if maxSize > 0 && res.ContentLength > maxSize {
+		return fmt.Errorf("value size %d exceeds %d: %w", res.ContentLength, maxSize, ErrValueTooLarge)
+	}
+
+	out.Value = nil
+	out.Chunks = nil
+	out.Version, _ = strconv.ParseUint(res.Header.Get(versionHeader), 10, 64)
+	out.Codec = res.Header.Get(codecHeader)
+	out.RawSize, _ = strconv.ParseInt(res.Header.Get(rawSizeHeader), 10, 64)
+	var total int64
+	for {
+		// 最多多读 1 字节, 用于发现超过 maxSize 的值
+		n := chunkSize
+		if maxSize > 0 && n > maxSize-total+1 {
+			n = maxSize - total + 1
+		}
+		chunk := make([]byte, n)
+		read, err := io.ReadFull(res.Body, chunk)
+		if read > 0 {
+			out.Chunks = append(out.Chunks, chunk[:read])
+			total += int64(read)
+		}
+		if maxSize > 0 && total > maxSize {
+			return fmt.Errorf("value size exceeds %d: %w", maxSize, ErrValueTooLarge)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading response body: %v", err)
+		}
+	}
+	if res.ContentLength >= 0 && total != res.ContentLength {
+		return fmt.Errorf("reading response body: got %d bytes, want %d", total, res.ContentLength)
+	}
+	return nil
+}
+
+// WatchPeer 订阅远程节点 baseURL (如 http://localhost:8001) 上某个 Group 的变更事件
+// ctx 取消或连接断开时返回的通道会被关闭
+// 只能收到该节点本地的事件, 订阅整个集群时需要对每个节点分别调用, 不同节点的 Version 相互独立
+func WatchPeer(ctx context.Context, baseURL string, group string, keys []string, prefixes []string) (<-chan Event, error) {
+	query := url.Values{}
+	for _, key := range keys {
+		query.Add("key", key)
+	}
+	for _, prefix := range prefixes {
+		query.Add("prefix", prefix)
+	}
+	u := fmt.Sprintf("%v%v%v%v?%v", baseURL, defaultBasePath, defaultWatchPath, url.QueryEscape(group), query.Encode())
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("server returned: %v", res.Status)
+	}
+
+	events := make(chan Event, defaultWatchBuffer)
+	go func() {
+		defer close(events)
+		defer res.Body.Close()
+		dec := json.NewDecoder(res.Body)
+		for {
+			var event Event
+			if err := dec.Decode(&event); err != nil {
+				return
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
 var _ PeerPicker = (*HTTPPool)(nil)
+var _ PeerSetter = (*httpGetter)(nil)
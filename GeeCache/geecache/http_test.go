@@ -0,0 +1,157 @@
+package geecache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	pb "geecache/geecachepb"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHTTPGetterNotFound(t *testing.T) {
+	mustNewGroup(t, "http-not-found", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			if key == "Tom" {
+				return []byte("630"), nil
+			}
+			return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+		}))
+	srv := httptest.NewServer(NewHTTPPool(""))
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + defaultBasePath}
+
+	c.Convey("远程节点的 404 转换为 ErrNotFound", t, func() {
+		res := &pb.Response{}
+		err := getter.Get(context.Background(), &pb.Request{Group: "http-not-found", Key: "Tom"}, res)
+		c.So(err, c.ShouldBeNil)
+		c.So(string(res.GetValue()), c.ShouldEqual, "630")
+
+		err = getter.Get(context.Background(), &pb.Request{Group: "http-not-found", Key: "Nobody"}, res)
+		c.So(errors.Is(err, ErrNotFound), c.ShouldBeTrue)
+
+		// group 不存在不是 key 不存在
+		err = getter.Get(context.Background(), &pb.Request{Group: "no-such-group", Key: "Tom"}, res)
+		c.So(err, c.ShouldNotBeNil)
+		c.So(errors.Is(err, ErrNotFound), c.ShouldBeFalse)
+	})
+}
+
+func TestHTTPGetterChunked(t *testing.T) {
+	large := strings.Repeat("0123456789", 100)
+	g := mustNewGroup(t, "http-chunked", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			if key == "large" {
+				return []byte(large), nil
+			}
+			return []byte(strings.Repeat(key, 1000)), nil
+		}))
+	g.SetChunkSize(64)
+	g.SetMaxValueSize(int64(len(large)))
+	srv := httptest.NewServer(NewHTTPPool(""))
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + defaultBasePath}
+
+	c.Convey("大值分块传输", t, func() {
+		res := &pb.Response{}
+		err := getter.Get(context.Background(), &pb.Request{Group: "http-chunked", Key: "large"}, res)
+		c.So(err, c.ShouldBeNil)
+		c.So(res.GetValue(), c.ShouldBeEmpty)
+		c.So(len(res.GetChunks()), c.ShouldEqual, 16)
+		c.So(string(bytes.Join(res.GetChunks(), nil)), c.ShouldEqual, large)
+
+		// 请求方的限制比远程节点小
+		err = getter.Get(context.Background(), &pb.Request{Group: "http-chunked", Key: "large", MaxChunkSize: 32}, res)
+		c.So(errors.Is(err, ErrValueTooLarge), c.ShouldBeTrue)
+		err = getter.Get(context.Background(), &pb.Request{Group: "http-chunked", Key: "large", MaxValueSize: 999}, res)
+		c.So(errors.Is(err, ErrValueTooLarge), c.ShouldBeTrue)
+		err = getter.Get(context.Background(), &pb.Request{Group: "http-chunked", Key: "large", MaxValueSize: 1000}, res)
+		c.So(err, c.ShouldBeNil)
+
+		r, err := g.GetReader("large")
+		c.So(err, c.ShouldBeNil)
+		b, _ := ioutil.ReadAll(r)
+		c.So(string(b), c.ShouldEqual, large)
+
+		// 超过最大值
+		_, err = g.Get("xx")
+		c.So(errors.Is(err, ErrValueTooLarge), c.ShouldBeTrue)
+		c.So(errors.Is(g.Set("xx", make([]byte, len(large)+1)), ErrValueTooLarge), c.ShouldBeTrue)
+	})
+}
+
+func TestReadChunksLimit(t *testing.T) {
+	// 不带 Content-Length 的流, 只能边读边检查总大小
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", streamContentType)
+		w.Header().Set(chunkSizeHeader, "64")
+		for i := 0; i < 10; i++ {
+			w.Write(make([]byte, 64))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + "/"}
+
+	c.Convey("流式响应超过请求方的最大值", t, func() {
+		res := &pb.Response{}
+		err := getter.Get(context.Background(), &pb.Request{Group: "g", Key: "k", MaxValueSize: 100}, res)
+		c.So(errors.Is(err, ErrValueTooLarge), c.ShouldBeTrue)
+		err = getter.Get(context.Background(), &pb.Request{Group: "g", Key: "k", MaxValueSize: 640}, res)
+		c.So(err, c.ShouldBeNil)
+		c.So(len(bytes.Join(res.GetChunks(), nil)), c.ShouldEqual, 640)
+	})
+}
+
+func TestReadBodyLimit(t *testing.T) {
+	// 关闭了分块的节点整体编码返回大值
+	body, _ := proto.Marshal(&pb.Response{Value: make([]byte, 4096)})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(body)
+	}))
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + "/"}
+
+	c.Convey("整体编码的响应超过请求方的最大值", t, func() {
+		res := &pb.Response{}
+		err := getter.Get(context.Background(), &pb.Request{Group: "g", Key: "k", MaxValueSize: 100}, res)
+		c.So(errors.Is(err, ErrValueTooLarge), c.ShouldBeTrue)
+		err = getter.Get(context.Background(), &pb.Request{Group: "g", Key: "k", MaxValueSize: 4096}, res)
+		c.So(err, c.ShouldBeNil)
+		c.So(len(res.GetValue()), c.ShouldEqual, 4096)
+	})
+}
+
+func TestHTTPGetterWrite(t *testing.T) {
+	g := mustNewGroup(t, "http-write", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+	g.SetMaxValueSize(8)
+	srv := httptest.NewServer(NewHTTPPool(""))
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + defaultBasePath}
+
+	c.Convey("转发的写入在远程节点执行", t, func() {
+		c.So(getter.Put(context.Background(), "http-write", "Tom", []byte("630")), c.ShouldBeNil)
+		v, err := g.Get("Tom")
+		c.So(err, c.ShouldBeNil)
+		c.So(v.String(), c.ShouldEqual, "630")
+
+		err = getter.Put(context.Background(), "http-write", "Tom", make([]byte, 9))
+		c.So(errors.Is(err, ErrValueTooLarge), c.ShouldBeTrue)
+
+		c.So(getter.Delete(context.Background(), "http-write", "Tom"), c.ShouldBeNil)
+		v, err = g.Get("Tom")
+		c.So(err, c.ShouldBeNil)
+		c.So(v.String(), c.ShouldEqual, "Tom")
+	})
+}
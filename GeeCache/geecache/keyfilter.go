@@ -0,0 +1,117 @@
+package geecache
+
+import (
+	"fmt"
+	"geecache/bloom"
+	"log"
+	"sync"
+	"time"
+)
+
+// KeyEnumerator 列出数据源中所有合法的 key, 用于构建布隆过滤器
+type KeyEnumerator interface {
+	Keys() ([]string, error)
+}
+
+// KeysFunc 定义函数类型实现 KeyEnumerator 接口
+type KeysFunc func() ([]string, error)
+
+func (f KeysFunc) Keys() ([]string, error) {
+	return f()
+}
+
+// keyFilter 保存当前的布隆过滤器, 并定期重新构建
+type keyFilter struct {
+	enum KeyEnumerator
+	rate float64 // 期望的误判率
+
+	mu     sync.RWMutex
+	filter *bloom.Filter
+	stop   chan struct{}
+}
+
+// refresh 重新枚举所有 key 构建过滤器, 失败时保留旧的过滤器
+func (f *keyFilter) refresh() error {
+	keys, err := f.enum.Keys()
+	if err != nil {
+		return err
+	}
+	filter := bloom.New(len(keys), f.rate)
+	for _, key := range keys {
+		filter.Add(key)
+	}
+	f.mu.Lock()
+	f.filter = filter
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *keyFilter) has(key string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.filter.Has(key)
+}
+
+func (f *keyFilter) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := f.refresh(); err != nil {
+				log.Println("[GeeCache] Failed to refresh key filter.", err)
+			}
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+// EnableKeyFilter 使用 enum 列出的 key 构建误判率约为 rate 的布隆过滤器,
+// 缓存未命中且一定不存在的 key 直接返回 ErrNotFound, 不再访问其他节点和 Getter
+// interval 大于 0 时按该间隔重新构建, 第一次构建失败时返回错误且不启用过滤器
+// 可能存在但最终没有找到的 key 计入 Stats 中的 FilterFalsePositives, 需要在开始服务之前调用
+func (g *Group) EnableKeyFilter(enum KeyEnumerator, rate float64, interval time.Duration) error {
+	f := &keyFilter{enum: enum, rate: rate, stop: make(chan struct{})}
+	if err := f.refresh(); err != nil {
+		return err
+	}
+	g.filterMu.Lock()
+	old := g.keyFilter
+	g.keyFilter = f
+	g.filterMu.Unlock()
+	if old != nil {
+		close(old.stop)
+	}
+	if interval > 0 {
+		go f.run(interval)
+	}
+	return nil
+}
+
+// DisableKeyFilter 关闭布隆过滤器并停止定期构建
+func (g *Group) DisableKeyFilter() {
+	g.filterMu.Lock()
+	f := g.keyFilter
+	g.keyFilter = nil
+	g.filterMu.Unlock()
+	if f != nil {
+		close(f.stop)
+	}
+}
+
+// RefreshKeyFilter 立即重新构建布隆过滤器
+func (g *Group) RefreshKeyFilter() error {
+	f := g.filter()
+	if f == nil {
+		return fmt.Errorf("key filter is not enabled")
+	}
+	return f.refresh()
+}
+
+// filter 返回当前的布隆过滤器, 可以与 EnableKeyFilter 和 DisableKeyFilter 并发调用
+func (g *Group) filter() *keyFilter {
+	g.filterMu.RLock()
+	defer g.filterMu.RUnlock()
+	return g.keyFilter
+}
@@ -0,0 +1,94 @@
+package geecache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func TestKeyFilter(t *testing.T) {
+	var lock sync.Mutex
+	keys := []string{"Tom", "Jack", "Sam"}
+	enum := KeysFunc(func() ([]string, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), keys...), nil
+	})
+	calls := 0
+	g := mustNewGroup(t, "key-filter", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		calls++
+		if key == "Sam" {
+			return nil, ErrNotFound
+		}
+		return []byte(key), nil
+	}))
+	if err := g.EnableKeyFilter(enum, 0.01, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	defer g.DisableKeyFilter()
+
+	c.Convey("一定不存在的 key 不会调用 Getter", t, func() {
+		_, err := g.Get("Tom")
+		c.So(err, c.ShouldBeNil)
+		_, err = g.Get("Nobody")
+		c.So(errors.Is(err, ErrNotFound), c.ShouldBeTrue)
+		_, err = g.Get("Sam")
+		c.So(errors.Is(err, ErrNotFound), c.ShouldBeTrue)
+		c.So(calls, c.ShouldEqual, 2)
+
+		stats := g.Stats()
+		c.So(stats.FilterRejects, c.ShouldEqual, 1)
+		c.So(stats.FilterFalsePositives, c.ShouldEqual, 1)
+
+		// 定期重新构建后新的 key 可以通过
+		lock.Lock()
+		keys = append(keys, "Nobody")
+		lock.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		_, err = g.Get("Nobody")
+		c.So(err, c.ShouldBeNil)
+	})
+
+	c.Convey("第一次构建失败时不启用", t, func() {
+		g := mustNewGroup(t, "key-filter-error", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+		err := g.EnableKeyFilter(KeysFunc(func() ([]string, error) {
+			return nil, errors.New("unavailable")
+		}), 0.01, 0)
+		c.So(err, c.ShouldNotBeNil)
+		c.So(g.RefreshKeyFilter(), c.ShouldNotBeNil)
+		_, err = g.Get("Tom")
+		c.So(err, c.ShouldBeNil)
+	})
+}
+
+func TestKeyFilterConcurrentDisable(t *testing.T) {
+	g := mustNewGroup(t, "key-filter-concurrent", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+	enum := KeysFunc(func() ([]string, error) {
+		return []string{"Tom"}, nil
+	})
+
+	c.Convey("开关过滤器时可以并发读取", t, func() {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 100; i++ {
+				g.Get("Tom")
+			}
+		}()
+		for i := 0; i < 100; i++ {
+			c.So(g.EnableKeyFilter(enum, 0.01, 0), c.ShouldBeNil)
+			g.DisableKeyFilter()
+		}
+		<-done
+		c.So(DestroyGroup("key-filter-concurrent"), c.ShouldBeTrue)
+	})
+}
@@ -0,0 +1,85 @@
+package geecache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGroupLifecycle(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	})
+	first := mustNewGroup(t, "lifecycle", 2<<10, getter)
+	watcher := first.Watch(nil, nil)
+
+	c.Convey("同名的 Group 注销之后才能重新创建", t, func() {
+		_, err := NewGroup("lifecycle", 2<<10, getter, nil)
+		c.So(errors.Is(err, ErrGroupExists), c.ShouldBeTrue)
+		c.So(GetGroup("lifecycle"), c.ShouldEqual, first)
+
+		c.So(DestroyGroup("lifecycle"), c.ShouldBeTrue)
+		c.So(DestroyGroup("lifecycle"), c.ShouldBeFalse)
+		c.So(GetGroup("lifecycle"), c.ShouldBeNil)
+		_, ok := <-watcher.Events()
+		c.So(ok, c.ShouldBeFalse)
+		watcher.Close()
+
+		second, err := NewGroup("lifecycle", 2<<10, getter, nil)
+		c.So(err, c.ShouldBeNil)
+		c.So(GetGroup("lifecycle"), c.ShouldEqual, second)
+		DestroyGroup("lifecycle")
+	})
+}
+
+type loadRecord struct {
+	key    string
+	source LoadSource
+	err    error
+}
+
+func TestGroupHooks(t *testing.T) {
+	var lock sync.Mutex
+	var hits, evicted []string
+	var loads []loadRecord
+	opts := &GroupOptions{
+		OnEvict: func(key string, value ByteView) {
+			evicted = append(evicted, key)
+		},
+		OnLoad: func(key string, source LoadSource, d time.Duration, err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			loads = append(loads, loadRecord{key, source, err})
+		},
+		OnHit: func(key string) {
+			hits = append(hits, key)
+		},
+	}
+	g, err := NewGroup("hooks", 16, GetterFunc(func(key string) ([]byte, error) {
+		return []byte("12345678"), nil
+	}), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { DestroyGroup("hooks") })
+
+	g.Get("k1")
+	g.Get("k1")
+	g.Get("k2") // 挤出 k1
+	g.RegisterPeers(&remotePeers{})
+	g.Get("remote")
+
+	c.Convey("回调在命中, 加载和淘汰时被调用", t, func() {
+		c.So(hits, c.ShouldResemble, []string{"k1"})
+		c.So(evicted, c.ShouldResemble, []string{"k1"})
+		c.So(loads, c.ShouldResemble, []loadRecord{
+			{"k1", LoadFromLocal, nil},
+			{"k2", LoadFromLocal, nil},
+			{"remote", LoadFromPeer, nil},
+		})
+		c.So(LoadFromPeer.String(), c.ShouldEqual, "peer")
+	})
+}
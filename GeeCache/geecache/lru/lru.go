@@ -38,6 +38,33 @@ func (c *Cache) Get(key string) (value Value, ok bool) {
 	return
 }
 
+// 查看记录但不更新其访问顺序
+func (c *Cache) Peek(key string) (value Value, ok bool) {
+	if ele, ok := c.cache[key]; ok {
+		return ele.Value.(*entry).value, true
+	}
+	return
+}
+
+// 返回队首 (最久未使用) 的记录, 即下一个将被淘汰的记录
+func (c *Cache) Oldest() (key string, value Value, ok bool) {
+	if ele := c.ll.Back(); ele != nil {
+		kv := ele.Value.(*entry)
+		return kv.key, kv.value, true
+	}
+	return
+}
+
+// 从最近使用到最久未使用依次遍历记录, fn 返回 false 时停止
+func (c *Cache) Range(fn func(key string, value Value) bool) {
+	for ele := c.ll.Front(); ele != nil; ele = ele.Next() {
+		kv := ele.Value.(*entry)
+		if !fn(kv.key, kv.value) {
+			return
+		}
+	}
+}
+
 func (c *Cache) RemoveOldest() {
 	// 这里约定 Back 为队首
 	ele := c.ll.Back()
@@ -53,6 +80,18 @@ func (c *Cache) RemoveOldest() {
 	}
 }
 
+// 主动删除某个记录, 不会触发 OnEvicted
+func (c *Cache) Remove(key string) bool {
+	if ele, ok := c.cache[key]; ok {
+		c.ll.Remove(ele)
+		kv := ele.Value.(*entry)
+		delete(c.cache, kv.key)
+		c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len())
+		return true
+	}
+	return false
+}
+
 func (c *Cache) Add(key string, value Value) {
 	if ele, ok := c.cache[key]; ok {
 		c.ll.MoveToFront(ele)
@@ -74,3 +113,21 @@ func (c *Cache) Add(key string, value Value) {
 func (c *Cache) Len() int {
 	return c.ll.Len()
 }
+
+// 当前使用内存
+func (c *Cache) Bytes() int64 {
+	return c.nbytes
+}
+
+// 最大使用内存, 0 表示不限制
+func (c *Cache) MaxBytes() int64 {
+	return c.maxBytes
+}
+
+// 调整最大使用内存, 超出的部分立即淘汰
+func (c *Cache) SetMaxBytes(maxBytes int64) {
+	c.maxBytes = maxBytes
+	for c.maxBytes != 0 && c.nbytes > c.maxBytes {
+		c.RemoveOldest()
+	}
+}
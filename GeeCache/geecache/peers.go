@@ -1,15 +1,31 @@
 package geecache
 
-import pb "geecache/geecachepb"
+import (
+	"context"
+	pb "geecache/geecachepb"
+)
 
 type PeerPicker interface {
 	PickPeer(key string) (peer PeerGetter, ok bool)
 }
 
+// OwnerPicker 是可以选出 key 在哈希环上负责节点的 PeerPicker
+// PickPeer 可能为了分摊负载选择其他节点, 写请求必须由负责节点执行, 使用 PickOwner
+type OwnerPicker interface {
+	PickOwner(key string) (peer PeerGetter, ok bool)
+}
+
 // type PeerGetter interface {
 // 	Get(group string, key string) ([]byte, error)
 // }
 
+// ctx 携带追踪信息, 取消时应当中止请求
 type PeerGetter interface {
-	Get(in *pb.Request, out *pb.Response) error
+	Get(ctx context.Context, in *pb.Request, out *pb.Response) error
+}
+
+// PeerSetter 是可以执行 CompareAndSet 的节点, 写请求会被路由到负责 key 的节点上
+// 版本不一致时返回的错误应当包装 ErrVersionConflict, 并在 out 中带上当前版本
+type PeerSetter interface {
+	CompareAndSet(ctx context.Context, in *pb.CASRequest, out *pb.CASResponse) error
 }
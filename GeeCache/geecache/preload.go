@@ -0,0 +1,108 @@
+package geecache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultPreloadParallelism 是 Preload 的默认并发数
+const DefaultPreloadParallelism = 8
+
+// PreloadProgress 是预热任务的进度
+type PreloadProgress struct {
+	Total    int  `json:"total"`
+	Loaded   int  `json:"loaded"`   // 加载成功的 key 数
+	Failed   int  `json:"failed"`   // 加载失败的 key 数
+	Done     bool `json:"done"`     // 任务已结束
+	Canceled bool `json:"canceled"` // 任务被取消, 剩余的 key 不再加载
+}
+
+// PreloadJob 是正在后台执行的预热任务
+type PreloadJob struct {
+	total    int
+	loaded   int64
+	failed   int64
+	canceled bool // 在 done 关闭之前写入
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// Preload 在后台以默认并发数加载 keys, 每个 key 都经过正常的加载流程, 会被缓存到负责它的节点上
+func (g *Group) Preload(keys []string) *PreloadJob {
+	return g.PreloadContext(context.Background(), keys, DefaultPreloadParallelism)
+}
+
+// PreloadContext 与 Preload 相同, 最多同时加载 parallelism 个 key, ctx 取消时任务随之取消
+func (g *Group) PreloadContext(ctx context.Context, keys []string, parallelism int) *PreloadJob {
+	if parallelism <= 0 {
+		parallelism = DefaultPreloadParallelism
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	job := &PreloadJob{
+		total:  len(keys),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	pending := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range pending {
+				if _, err := g.GetContext(ctx, key); err != nil {
+					atomic.AddInt64(&job.failed, 1)
+				} else {
+					atomic.AddInt64(&job.loaded, 1)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(job.done)
+		defer cancel()
+	send:
+		for _, key := range keys {
+			select {
+			case pending <- key:
+			case <-ctx.Done():
+				break send
+			}
+		}
+		close(pending)
+		wg.Wait()
+		job.canceled = ctx.Err() != nil
+	}()
+	return job
+}
+
+// Progress 返回当前进度
+func (j *PreloadJob) Progress() PreloadProgress {
+	p := PreloadProgress{
+		Total:  j.total,
+		Loaded: int(atomic.LoadInt64(&j.loaded)),
+		Failed: int(atomic.LoadInt64(&j.failed)),
+	}
+	select {
+	case <-j.done:
+		p.Done = true
+		p.Canceled = j.canceled
+	default:
+	}
+	return p
+}
+
+// Cancel 取消任务, 剩余的 key 不再加载; 正在加载的 key 停止等待并计为失败,
+// 但已经开始的加载不会中断, 仍会在后台完成并写入缓存
+func (j *PreloadJob) Cancel() {
+	j.cancel()
+}
+
+// Wait 等待任务结束并返回最终进度
+func (j *PreloadJob) Wait() PreloadProgress {
+	<-j.done
+	return j.Progress()
+}
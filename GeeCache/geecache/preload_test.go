@@ -0,0 +1,62 @@
+package geecache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPreload(t *testing.T) {
+	var lock sync.Mutex
+	running, maxRunning := 0, 0
+	g := mustNewGroup(t, "preload", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		lock.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+		time.Sleep(time.Millisecond)
+		lock.Lock()
+		running--
+		lock.Unlock()
+		if key == "bad" {
+			return nil, ErrNotFound
+		}
+		return []byte(key), nil
+	}))
+
+	keys := []string{"bad"}
+	for i := 0; i < 20; i++ {
+		keys = append(keys, "key"+strconv.Itoa(i))
+	}
+
+	c.Convey("以有限的并发数加载所有 key", t, func() {
+		p := g.PreloadContext(context.Background(), keys, 3).Wait()
+		c.So(p, c.ShouldResemble, PreloadProgress{Total: 21, Loaded: 20, Failed: 1, Done: true})
+		c.So(maxRunning, c.ShouldBeLessThanOrEqualTo, 3)
+		c.So(g.mainCache.lru.Len(), c.ShouldEqual, 20)
+	})
+}
+
+func TestPreloadCancel(t *testing.T) {
+	unblock := make(chan struct{})
+	g := mustNewGroup(t, "preload-cancel", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		<-unblock
+		return []byte(key), nil
+	}))
+	job := g.PreloadContext(context.Background(), []string{"a", "b", "c", "d"}, 1)
+
+	c.Convey("取消后剩余的 key 不再加载", t, func() {
+		job.Cancel()
+		close(unblock)
+		p := job.Wait()
+		c.So(p.Done, c.ShouldBeTrue)
+		c.So(p.Canceled, c.ShouldBeTrue)
+		c.So(p.Loaded+p.Failed, c.ShouldBeLessThan, 4)
+	})
+}
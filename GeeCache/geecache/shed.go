@@ -0,0 +1,100 @@
+package geecache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// limiter 限制同时处理的请求数, 超出的请求在有界队列中最多等待 timeout
+type limiter struct {
+	slots   chan struct{} // 容量为最大并发数
+	queue   chan struct{} // 容量为最大排队数
+	timeout time.Duration
+}
+
+func newLimiter(maxInflight, maxQueue int, timeout time.Duration) *limiter {
+	return &limiter{
+		slots:   make(chan struct{}, maxInflight),
+		queue:   make(chan struct{}, maxQueue),
+		timeout: timeout,
+	}
+}
+
+// acquire 获取一个处理名额, 队列已满, 等待超时或 ctx 取消时返回 false
+func (l *limiter) acquire(ctx context.Context) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	select {
+	case l.queue <- struct{}{}:
+	default:
+		return false
+	}
+	defer func() { <-l.queue }()
+
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (l *limiter) release() {
+	<-l.slots
+}
+
+// retryAfter 返回建议调用方等待的秒数, 至少为 1
+func (l *limiter) retryAfter() int {
+	seconds := int((l.timeout + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// SetConcurrencyLimit 限制本节点同时处理的 Get、写入、_cas 和 _handoff 请求数为 maxInflight,
+// 超出的请求最多 maxQueue 个排队等待 queueTimeout, 其余请求直接返回 503 和 Retry-After,
+// Get 的调用方收到后在本地加载, 其余请求返回 ErrPeerOverloaded; _watch 长连接和 _flush 不受限制
+// maxInflight 为 0 时不限制, 参数为负数时返回错误, 需要在开始服务之前调用
+func (p *HTTPPool) SetConcurrencyLimit(maxInflight, maxQueue int, queueTimeout time.Duration) error {
+	if maxInflight < 0 || maxQueue < 0 || queueTimeout < 0 {
+		return fmt.Errorf("concurrency limit must not be negative")
+	}
+	if maxInflight == 0 {
+		p.limiter = nil
+		return nil
+	}
+	p.limiter = newLimiter(maxInflight, maxQueue, queueTimeout)
+	return nil
+}
+
+// acquire 在启用并发限制时占用一个名额, 返回 false 时已经回复 503
+func (p *HTTPPool) acquire(w http.ResponseWriter, r *http.Request) bool {
+	if p.limiter == nil {
+		return true
+	}
+	if !p.limiter.acquire(r.Context()) {
+		w.Header().Set("Retry-After", strconv.Itoa(p.limiter.retryAfter()))
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// release 释放 acquire 占用的名额
+func (p *HTTPPool) release() {
+	if p.limiter != nil {
+		p.limiter.release()
+	}
+}
@@ -0,0 +1,108 @@
+package geecache
+
+import (
+	"context"
+	"errors"
+	pb "geecache/geecachepb"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(1, 1, 20*time.Millisecond)
+
+	c.Convey("超出并发数的请求排队, 队列满或超时后被拒绝", t, func() {
+		c.So(l.acquire(context.Background()), c.ShouldBeTrue)
+
+		go func() {
+			time.Sleep(5 * time.Millisecond)
+			l.release()
+		}()
+		c.So(l.acquire(context.Background()), c.ShouldBeTrue)
+
+		// 名额被占用, 排队超时
+		c.So(l.acquire(context.Background()), c.ShouldBeFalse)
+
+		l.queue <- struct{}{}
+		start := time.Now()
+		c.So(l.acquire(context.Background()), c.ShouldBeFalse)
+		c.So(time.Since(start), c.ShouldBeLessThan, 10*time.Millisecond)
+		<-l.queue
+
+		c.So(l.retryAfter(), c.ShouldEqual, 1)
+	})
+}
+
+func TestLoadShedding(t *testing.T) {
+	started, unblock := make(chan struct{}), make(chan struct{})
+	mustNewGroup(t, "shed-remote", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		if key == "slow" {
+			close(started)
+			<-unblock
+		}
+		return []byte("remote"), nil
+	}))
+	pool := NewHTTPPool("")
+	pool.SetConcurrencyLimit(1, 0, 0)
+	srv := httptest.NewServer(pool)
+	defer srv.Close()
+	getter := &httpGetter{baseURL: srv.URL + defaultBasePath}
+
+	go getter.Get(context.Background(), &pb.Request{Group: "shed-remote", Key: "slow"}, &pb.Response{})
+	<-started
+	defer close(unblock)
+
+	c.Convey("过载时返回 503, 调用方在本地加载", t, func() {
+		res, err := http.Get(srv.URL + defaultBasePath + "shed-remote/Tom")
+		c.So(err, c.ShouldBeNil)
+		res.Body.Close()
+		c.So(res.StatusCode, c.ShouldEqual, http.StatusServiceUnavailable)
+		c.So(res.Header.Get("Retry-After"), c.ShouldEqual, "1")
+
+		g := mustNewGroup(t, "shed-local", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+			return []byte("local"), nil
+		}))
+		g.RegisterPeers(&renamePeers{getter: getter, group: "shed-remote"})
+		view, err := g.Get("Tom")
+		c.So(err, c.ShouldBeNil)
+		c.So(view.String(), c.ShouldEqual, "local")
+		c.So(getter.retryAt, c.ShouldBeGreaterThan, time.Now().UnixNano())
+
+		// 退避期间不再发送请求
+		err = getter.Get(context.Background(), &pb.Request{Group: "shed-remote", Key: "Jack"}, &pb.Response{})
+		c.So(errors.Is(err, ErrPeerOverloaded), c.ShouldBeTrue)
+		c.So(getter.inflight, c.ShouldEqual, 1)
+
+		stats := g.Stats()
+		c.So(stats.PeerOverloads, c.ShouldEqual, 1)
+		c.So(stats.PeerErrors, c.ShouldEqual, 0)
+		c.So(stats.LocalLoads, c.ShouldEqual, 1)
+	})
+
+	c.Convey("写入、CAS 和移交同样受并发限制", t, func() {
+		writer := &httpGetter{baseURL: srv.URL + defaultBasePath}
+		err := writer.Put(context.Background(), "shed-remote", "Tom", []byte("v"))
+		c.So(errors.Is(err, ErrPeerOverloaded), c.ShouldBeTrue)
+		err = writer.CompareAndSet(context.Background(), &pb.CASRequest{Group: "shed-remote", Key: "Tom"}, &pb.CASResponse{})
+		c.So(errors.Is(err, ErrPeerOverloaded), c.ShouldBeTrue)
+		err = writer.handoff(context.Background(), &pb.HandoffRequest{Group: "shed-remote"})
+		c.So(err, c.ShouldNotBeNil)
+	})
+}
+
+func TestSetConcurrencyLimit(t *testing.T) {
+	pool := NewHTTPPool("")
+	c.Convey("参数不能为负数, maxInflight 为 0 时不限制", t, func() {
+		c.So(pool.SetConcurrencyLimit(-1, 0, 0), c.ShouldNotBeNil)
+		c.So(pool.SetConcurrencyLimit(1, -1, 0), c.ShouldNotBeNil)
+		c.So(pool.SetConcurrencyLimit(1, 0, -time.Second), c.ShouldNotBeNil)
+		c.So(pool.SetConcurrencyLimit(1, 1, time.Second), c.ShouldBeNil)
+		c.So(pool.limiter, c.ShouldNotBeNil)
+		c.So(pool.SetConcurrencyLimit(0, 0, 0), c.ShouldBeNil)
+		c.So(pool.limiter, c.ShouldBeNil)
+	})
+}
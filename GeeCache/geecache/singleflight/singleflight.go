@@ -1,14 +1,15 @@
 package singleflight
 
 import (
+	"context"
 	"sync"
 )
 
 // 正在进行中的，或者已经结束的请求.
 type call struct {
-	wg  sync.WaitGroup // 避免重入
-	val interface{}
-	err error
+	done chan struct{} // 请求结束时关闭, 避免重入
+	val  interface{}
+	err  error
 }
 
 // 管理不同 key 的请求 call.
@@ -18,29 +19,55 @@ type Group struct {
 }
 
 func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	c, leader := g.join(key)
+	if !leader {
+		<-c.done            // 有请求正在进行, 等待
+		return c.val, c.err // 请求结束, 返回结果
+	}
+
+	g.run(key, c, fn) // 调用 function 发起请求
+	return c.val, c.err
+}
+
+// DoContext 与 Do 相同, 但 ctx 结束时立即返回 ctx.Err(), fn 在单独的 goroutine 中继续执行,
+// 结果仍然交给其他等待的调用方; shared 表示结果来自其他调用方发起的请求
+func (g *Group) DoContext(ctx context.Context, key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	c, leader := g.join(key)
+	if leader {
+		go g.run(key, c, fn)
+	}
+
+	select {
+	case <-c.done:
+		return c.val, c.err, !leader
+	case <-ctx.Done():
+		return nil, ctx.Err(), !leader
+	}
+}
+
+// join 返回 key 对应的请求, leader 为 true 表示请求是新创建的, 需要由调用方执行
+func (g *Group) join(key string) (c *call, leader bool) {
 	g.mu.Lock()
+	defer g.mu.Unlock()
 	if g.m == nil {
 		g.m = make(map[string]*call)
 	}
 
 	if c, ok := g.m[key]; ok {
 		// 当前 key 对应的请求正在处理 or 已经处理过了
-		g.mu.Unlock()
-		c.wg.Wait()         // 有请求正在进行, 等待
-		return c.val, c.err // 请求结束, 返回结果
+		return c, false
 	}
 
-	c := new(call)
-	c.wg.Add(1)  // 发起请求前加锁
+	c = &call{done: make(chan struct{})}
 	g.m[key] = c // 将请求添加到 g.m 中, 表示已经有对应的请求在处理
-	g.mu.Unlock()
+	return c, true
+}
 
-	c.val, c.err = fn() // 调用 function 发起请求
-	c.wg.Done()         // 请求结束释放锁
+func (g *Group) run(key string, c *call, fn func() (interface{}, error)) {
+	c.val, c.err = fn()
+	close(c.done) // 请求结束, 唤醒等待的调用方
 
 	g.mu.Lock()
 	delete(g.m, key) // 更新 g.m
 	g.mu.Unlock()
-
-	return c.val, c.err
 }
@@ -0,0 +1,69 @@
+package geecache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats 是 Group 的运行统计
+type Stats struct {
+	Gets          int64    // Get 调用次数
+	CacheHits     int64    // 命中主缓存的次数
+	HotCacheHits  int64    // 命中热点缓存的次数
+	PeerLoads     int64    // 从其他节点获取成功的次数
+	PeerErrors    int64    // 从其他节点获取失败的次数
+	PeerOverloads int64    // 其他节点过载拒绝请求的次数, 不计入 PeerErrors
+	LocalLoads    int64    // 调用 Getter 成功的次数
+	LocalLoadErrs int64    // 调用 Getter 失败的次数
+	HotKeys       []string // 当前被复制到本节点的热点 key
+	DecodeErrors  int64    // ByteSlice 解压失败的次数, 由进程内所有 Group 共享
+
+	FilterRejects        int64 // 被布隆过滤器拦截的次数
+	FilterFalsePositives int64 // 通过了布隆过滤器但最终不存在的次数
+
+	Tenants map[string]TenantStats // 启用租户划分时每个租户的统计
+}
+
+// groupStats 保存原子计数器
+type groupStats struct {
+	gets          int64
+	cacheHits     int64
+	hotCacheHits  int64
+	peerLoads     int64
+	peerErrors    int64
+	peerOverloads int64
+	localLoads    int64
+	localLoadErrs int64
+
+	filterRejects        int64
+	filterFalsePositives int64
+}
+
+func (s *groupStats) add(counter *int64) {
+	atomic.AddInt64(counter, 1)
+}
+
+// Stats 返回当前统计的快照
+func (g *Group) Stats() Stats {
+	s := Stats{
+		Gets:          atomic.LoadInt64(&g.stats.gets),
+		CacheHits:     atomic.LoadInt64(&g.stats.cacheHits),
+		HotCacheHits:  atomic.LoadInt64(&g.stats.hotCacheHits),
+		PeerLoads:     atomic.LoadInt64(&g.stats.peerLoads),
+		PeerErrors:    atomic.LoadInt64(&g.stats.peerErrors),
+		PeerOverloads: atomic.LoadInt64(&g.stats.peerOverloads),
+		LocalLoads:    atomic.LoadInt64(&g.stats.localLoads),
+		LocalLoadErrs: atomic.LoadInt64(&g.stats.localLoadErrs),
+		DecodeErrors:  atomic.LoadInt64(&decodeErrors),
+
+		FilterRejects:        atomic.LoadInt64(&g.stats.filterRejects),
+		FilterFalsePositives: atomic.LoadInt64(&g.stats.filterFalsePositives),
+	}
+	s.Tenants = g.mainCache.tenantStats()
+	if g.hot != nil {
+		keys, demoted := g.hot.list(time.Now())
+		g.demote(demoted)
+		s.HotKeys = keys
+	}
+	return s
+}
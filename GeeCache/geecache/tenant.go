@@ -0,0 +1,219 @@
+package geecache
+
+import (
+	"fmt"
+	"geecache/lru"
+	"strings"
+)
+
+// defaultTenant 是 TenantFunc 返回空字符串时使用的租户
+const defaultTenant = ""
+
+// maxTenants 是单独统计的租户数上限, 超出后新出现的租户计入默认租户
+const maxTenants = 1024
+
+// TenantFunc 从 key 中提取租户
+type TenantFunc func(key string) string
+
+// TenantPrefix 以 key 中第一个 sep 之前的部分作为租户, 例如 "acme:1001" 属于 "acme"
+// 不包含 sep 的 key 属于默认租户 ""
+func TenantPrefix(sep string) TenantFunc {
+	return func(key string) string {
+		if i := strings.Index(key, sep); i >= 0 {
+			return key[:i]
+		}
+		return defaultTenant
+	}
+}
+
+// TenantStats 是单个租户的统计
+type TenantStats struct {
+	Hits   int64 // 命中本节点缓存的次数
+	Misses int64 // 未命中的次数
+	Bytes  int64 // 当前占用的字节数
+	Quota  int64 // 当前的配额, 0 表示不限制
+}
+
+// tenants 为每个租户维护独立的 LRU, 由 cache 的锁保护
+// 没有显式配额的租户平分剩余的容量, 有新租户出现或租户的条目被清空时重新分配
+type tenants struct {
+	tenantOf TenantFunc
+	quotas   map[string]int64
+	budget   int64 // 整个缓存的容量, 0 表示不限制
+	reserved int64 // 显式配额之和
+	caches   map[string]*lru.Cache
+	counts   map[string]*tenantCounts // 已登记的租户, 最多 maxTenants 个加上有显式配额的租户
+}
+
+type tenantCounts struct {
+	hits, misses int64
+}
+
+func newTenants(tenantOf TenantFunc, quotas map[string]int64, budget int64) *tenants {
+	t := &tenants{
+		tenantOf: tenantOf,
+		quotas:   make(map[string]int64, len(quotas)),
+		budget:   budget,
+		caches:   make(map[string]*lru.Cache),
+		counts:   make(map[string]*tenantCounts),
+	}
+	for tenant, quota := range quotas {
+		t.quotas[tenant] = quota
+		t.reserved += quota
+	}
+	return t
+}
+
+// tenantFor 返回 key 所属的租户, 并登记第一次出现的租户
+// 已经登记了 maxTenants 个租户时, 新出现的租户计入默认租户, 同一个 key 总是属于同一个租户
+func (t *tenants) tenantFor(key string) string {
+	tenant := t.tenantOf(key)
+	if _, ok := t.counts[tenant]; ok {
+		return tenant
+	}
+	if _, ok := t.quotas[tenant]; !ok && len(t.counts) >= maxTenants {
+		tenant = defaultTenant
+		if _, ok := t.counts[tenant]; ok {
+			return tenant
+		}
+	}
+	t.counts[tenant] = &tenantCounts{}
+	return tenant
+}
+
+func (t *tenants) lruFor(key string, create bool, onEvicted func(string, lru.Value)) *lru.Cache {
+	tenant := t.tenantFor(key)
+	if l, ok := t.caches[tenant]; ok || !create {
+		return l
+	}
+
+	quota, explicit := t.quotas[tenant]
+	l := lru.New(quota, onEvicted)
+	t.caches[tenant] = l
+	if !explicit {
+		t.reshare(tenant)
+	}
+	return l
+}
+
+// dropEmpty 在 key 所属租户的 LRU 被清空时删除该 LRU, 并把它的份额分给其他租户
+func (t *tenants) dropEmpty(key string) {
+	tenant := t.tenantFor(key)
+	l, ok := t.caches[tenant]
+	if !ok || l.Len() > 0 {
+		return
+	}
+	delete(t.caches, tenant)
+	if _, explicit := t.quotas[tenant]; !explicit {
+		t.reshare(tenant)
+	}
+}
+
+// reshare 重新计算没有显式配额的租户的公平份额, 超出新份额的条目立即淘汰
+// 只有占用了字节的租户和刚创建 LRU 的租户 adding 参与平分
+func (t *tenants) reshare(adding string) {
+	if t.budget == 0 {
+		return
+	}
+	shared := 0
+	for tenant, l := range t.caches {
+		if _, ok := t.quotas[tenant]; !ok && (l.Bytes() > 0 || tenant == adding) {
+			shared++
+		}
+	}
+	if shared == 0 {
+		return
+	}
+	share := (t.budget - t.reserved) / int64(shared)
+	if share < 1 {
+		// 0 对 LRU 表示不限制, 至少保留 1 字节使其立即淘汰
+		share = 1
+	}
+	for tenant, l := range t.caches {
+		if _, ok := t.quotas[tenant]; !ok {
+			l.SetMaxBytes(share)
+		}
+	}
+}
+
+func (t *tenants) record(key string, hit bool) {
+	n := t.counts[t.tenantFor(key)]
+	if hit {
+		n.hits++
+	} else {
+		n.misses++
+	}
+}
+
+func (t *tenants) rangeEntries(fn func(key string, value lru.Value) bool) {
+	for _, l := range t.caches {
+		stop := false
+		l.Range(func(key string, value lru.Value) bool {
+			stop = !fn(key, value)
+			return !stop
+		})
+		if stop {
+			return
+		}
+	}
+}
+
+func (t *tenants) stats() map[string]TenantStats {
+	stats := make(map[string]TenantStats)
+	for tenant, l := range t.caches {
+		s := stats[tenant]
+		s.Bytes, s.Quota = l.Bytes(), l.MaxBytes()
+		stats[tenant] = s
+	}
+	for tenant, n := range t.counts {
+		s := stats[tenant]
+		s.Hits, s.Misses = n.hits, n.misses
+		stats[tenant] = s
+	}
+	return stats
+}
+
+func (c *cache) setTenants(t *tenants) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tenants = t
+	c.lru = nil
+}
+
+// recordTenant 记录 key 所属租户的一次命中或未命中
+func (c *cache) recordTenant(key string, hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tenants != nil {
+		c.tenants.record(key, hit)
+	}
+}
+
+func (c *cache) tenantStats() map[string]TenantStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tenants == nil {
+		return nil
+	}
+	return c.tenants.stats()
+}
+
+// SetTenants 按 tenantOf 提取的租户划分主缓存, 每个租户有独立的 LRU, 一个租户的写入只会淘汰自己的条目
+// quotas 为部分租户指定字节配额, 其余租户平分剩余的容量; 配额之和必须小于 Group 的容量,
+// 否则没有配额的租户 (至少包括默认租户 "") 分不到空间, 任何写入都会被立即淘汰
+// 没有配额的租户最多单独统计 maxTenants 个, 之后出现的租户计入默认租户 ""
+// 需要在开始服务之前调用, 已缓存的条目会被清空
+func (g *Group) SetTenants(tenantOf TenantFunc, quotas map[string]int64) error {
+	var reserved int64
+	for tenant, quota := range quotas {
+		if quota <= 0 {
+			return fmt.Errorf("tenant %q: quota must be positive", tenant)
+		}
+		reserved += quota
+	}
+	if budget := g.mainCache.cacheBytes; budget > 0 && reserved >= budget {
+		return fmt.Errorf("tenant quotas %d leave no room in cache size %d for other tenants", reserved, budget)
+	}
+	g.mainCache.setTenants(newTenants(tenantOf, quotas, g.mainCache.cacheBytes))
+	return nil
+}
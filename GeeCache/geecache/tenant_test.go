@@ -0,0 +1,85 @@
+package geecache
+
+import (
+	"strconv"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTenants(t *testing.T) {
+	loads := make(map[string]int)
+	g := mustNewGroup(t, "tenants", 100, GetterFunc(func(key string) ([]byte, error) {
+		loads[key]++
+		return []byte("0123456789"), nil
+	}))
+
+	c.Convey("配额之和必须小于容量", t, func() {
+		c.So(g.SetTenants(TenantPrefix(":"), map[string]int64{"vip": 101}), c.ShouldNotBeNil)
+		c.So(g.SetTenants(TenantPrefix(":"), map[string]int64{"vip": 100}), c.ShouldNotBeNil)
+		c.So(g.SetTenants(TenantPrefix(":"), map[string]int64{"vip": 0}), c.ShouldNotBeNil)
+	})
+
+	c.Convey("一个租户的写入不会淘汰其他租户", t, func() {
+		c.So(g.SetTenants(TenantPrefix(":"), map[string]int64{"vip": 40}), c.ShouldBeNil)
+
+		g.Get("quiet:1")
+		for i := 0; i < 10; i++ {
+			g.Get("noisy:" + strconv.Itoa(i))
+		}
+		g.Get("vip:1")
+		g.Get("vip:2")
+
+		g.Get("quiet:1")
+		g.Get("vip:1")
+		g.Get("noisy:0")
+		c.So(loads["quiet:1"], c.ShouldEqual, 1)
+		c.So(loads["vip:1"], c.ShouldEqual, 1)
+		c.So(loads["noisy:0"], c.ShouldEqual, 2)
+
+		stats := g.Stats().Tenants
+		c.So(stats["quiet"], c.ShouldResemble, TenantStats{Hits: 1, Misses: 1, Bytes: 17, Quota: 30})
+		c.So(stats["noisy"], c.ShouldResemble, TenantStats{Hits: 0, Misses: 11, Bytes: 17, Quota: 30})
+		c.So(stats["vip"], c.ShouldResemble, TenantStats{Hits: 1, Misses: 2, Bytes: 30, Quota: 40})
+	})
+}
+
+func TestTenantPrefix(t *testing.T) {
+	tenantOf := TenantPrefix(":")
+	c.Convey("TenantPrefix 测试", t, func() {
+		c.So(tenantOf("acme:1001"), c.ShouldEqual, "acme")
+		c.So(tenantOf("acme:a:b"), c.ShouldEqual, "acme")
+		c.So(tenantOf("1001"), c.ShouldEqual, "")
+	})
+}
+
+func TestTenantsBounded(t *testing.T) {
+	g := mustNewGroup(t, "tenants-bounded", 1<<20, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+
+	c.Convey("租户数达到上限后新的租户计入默认租户", t, func() {
+		c.So(g.SetTenants(TenantPrefix(":"), nil), c.ShouldBeNil)
+		for i := 0; i < maxTenants+10; i++ {
+			g.Get("t" + strconv.Itoa(i) + ":k")
+		}
+		stats := g.Stats().Tenants
+		// 另外还有默认租户
+		c.So(len(stats), c.ShouldEqual, maxTenants+1)
+		c.So(stats[""].Misses, c.ShouldEqual, 10)
+		_, err := g.Get("t" + strconv.Itoa(maxTenants+1) + ":k")
+		c.So(err, c.ShouldBeNil)
+		c.So(g.Stats().Tenants[""].Hits, c.ShouldEqual, 1)
+	})
+
+	c.Convey("清空的租户不再占用份额", t, func() {
+		c.So(g.SetTenants(TenantPrefix(":"), nil), c.ShouldBeNil)
+		g.Get("a:1")
+		g.Get("b:1")
+		c.So(g.Stats().Tenants["a"].Quota, c.ShouldEqual, 1<<19)
+
+		g.mainCache.remove("b:1")
+		c.So(g.mainCache.tenants.caches, c.ShouldNotContainKey, "b")
+		c.So(g.Stats().Tenants["a"].Quota, c.ShouldEqual, 1<<20)
+	})
+}
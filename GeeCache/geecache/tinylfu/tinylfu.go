@@ -0,0 +1,160 @@
+package tinylfu
+
+import "hash/fnv"
+
+const (
+	depth      = 4  // Count-Min Sketch 的行数, 即哈希函数个数
+	maxCounter = 15 // 计数器上限, 与 4 bit 计数器的语义一致
+)
+
+// Sketch 是带周期性衰减的 Count-Min Sketch, 用来估算 key 的访问频率
+type Sketch struct {
+	rows       [depth][]uint8
+	mask       uint64 // 宽度为 2 的幂, 用掩码代替取模
+	additions  int    // 自上次衰减以来的计数次数
+	sampleSize int    // 达到该次数后所有计数器减半
+}
+
+// NewSketch 创建宽度不小于 width 的 Sketch
+func NewSketch(width int) *Sketch {
+	w := 1
+	for w < width {
+		w <<= 1
+	}
+	s := &Sketch{
+		mask:       uint64(w - 1),
+		sampleSize: 10 * w,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, w)
+	}
+	return s
+}
+
+// indexes 使用双重哈希为每一行生成一个下标
+func (s *Sketch) indexes(key string) [depth]uint64 {
+	return indexes(key, s.mask)
+}
+
+func indexes(key string, mask uint64) [depth]uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := sum&0xffffffff, sum>>32|1
+
+	var idx [depth]uint64
+	for i := range idx {
+		idx[i] = (h1 + uint64(i)*h2) & mask
+	}
+	return idx
+}
+
+// Increment 记录一次访问
+func (s *Sketch) Increment(key string) {
+	for i, idx := range s.indexes(key) {
+		if s.rows[i][idx] < maxCounter {
+			s.rows[i][idx]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.sampleSize {
+		s.reset()
+	}
+}
+
+// Estimate 返回 key 的估计访问次数, 取各行计数的最小值
+func (s *Sketch) Estimate(key string) uint8 {
+	min := uint8(maxCounter)
+	for i, idx := range s.indexes(key) {
+		if s.rows[i][idx] < min {
+			min = s.rows[i][idx]
+		}
+	}
+	return min
+}
+
+// reset 将所有计数器减半, 让过去的热点逐渐冷却
+func (s *Sketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
+
+// CountMin 是不衰减的 Count-Min Sketch, 计数器为 32 bit, 由调用方按需 Reset
+// 估计值只会偏大, 不是并发安全的
+type CountMin struct {
+	rows [depth][]uint32
+	mask uint64
+}
+
+// NewCountMin 创建宽度不小于 width 的 CountMin
+func NewCountMin(width int) *CountMin {
+	w := 1
+	for w < width {
+		w <<= 1
+	}
+	s := &CountMin{mask: uint64(w - 1)}
+	for i := range s.rows {
+		s.rows[i] = make([]uint32, w)
+	}
+	return s
+}
+
+// Add 记录一次访问, 返回 key 的估计访问次数
+func (s *CountMin) Add(key string) uint32 {
+	min := ^uint32(0)
+	for i, idx := range indexes(key, s.mask) {
+		if s.rows[i][idx] < ^uint32(0) {
+			s.rows[i][idx]++
+		}
+		if s.rows[i][idx] < min {
+			min = s.rows[i][idx]
+		}
+	}
+	return min
+}
+
+// Estimate 返回 key 的估计访问次数
+func (s *CountMin) Estimate(key string) uint32 {
+	min := ^uint32(0)
+	for i, idx := range indexes(key, s.mask) {
+		if s.rows[i][idx] < min {
+			min = s.rows[i][idx]
+		}
+	}
+	return min
+}
+
+// Reset 清空所有计数器
+func (s *CountMin) Reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] = 0
+		}
+	}
+}
+
+// TinyLFU 准入策略: 新 key 只有在估计频率高于待淘汰 key 时才被接纳
+// 不是并发安全的, 由调用方加锁
+type TinyLFU struct {
+	sketch *Sketch
+}
+
+// New 创建 TinyLFU, counters 一般取缓存预计容纳条目数的若干倍
+func New(counters int) *TinyLFU {
+	return &TinyLFU{sketch: NewSketch(counters)}
+}
+
+// Record 记录一次对 key 的访问
+func (t *TinyLFU) Record(key string) {
+	t.sketch.Increment(key)
+}
+
+// Admit 判断 candidate 是否可以替换 victim
+func (t *TinyLFU) Admit(candidate, victim string) bool {
+	return t.sketch.Estimate(candidate) > t.sketch.Estimate(victim)
+}
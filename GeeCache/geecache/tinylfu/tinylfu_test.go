@@ -0,0 +1,64 @@
+package tinylfu
+
+import (
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSketch(t *testing.T) {
+	s := NewSketch(100)
+	for i := 0; i < 5; i++ {
+		s.Increment("hot")
+	}
+	s.Increment("cold")
+
+	c.Convey("Sketch 测试", t, func() {
+		c.So(len(s.rows[0]), c.ShouldEqual, 128)
+		c.So(s.Estimate("hot"), c.ShouldEqual, 5)
+		c.So(s.Estimate("cold"), c.ShouldEqual, 1)
+		c.So(s.Estimate("none"), c.ShouldEqual, 0)
+	})
+}
+
+func TestSketchReset(t *testing.T) {
+	s := NewSketch(4)
+	for i := 0; i < 20; i++ {
+		s.Increment("hot")
+	}
+
+	c.Convey("计数器上限与周期性衰减", t, func() {
+		c.So(s.Estimate("hot"), c.ShouldEqual, maxCounter)
+		for i := 0; i < s.sampleSize; i++ {
+			s.Increment("other")
+		}
+		c.So(s.Estimate("hot"), c.ShouldBeLessThan, maxCounter)
+	})
+}
+
+func TestCountMin(t *testing.T) {
+	s := NewCountMin(100)
+	for i := 0; i < 100; i++ {
+		s.Add("hot")
+	}
+
+	c.Convey("计数不受上限限制, Reset 后清零", t, func() {
+		c.So(s.Add("hot"), c.ShouldEqual, 101)
+		c.So(s.Estimate("none"), c.ShouldEqual, 0)
+		s.Reset()
+		c.So(s.Estimate("hot"), c.ShouldEqual, 0)
+	})
+}
+
+func TestAdmit(t *testing.T) {
+	lfu := New(100)
+	lfu.Record("hot")
+	lfu.Record("hot")
+	lfu.Record("warm")
+
+	c.Convey("准入测试", t, func() {
+		c.So(lfu.Admit("hot", "warm"), c.ShouldBeTrue)
+		c.So(lfu.Admit("warm", "hot"), c.ShouldBeFalse)
+		c.So(lfu.Admit("new", "warm"), c.ShouldBeFalse)
+	})
+}
@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// FinishedSpan 是 InMemory 记录下来的已结束的 Span
+type FinishedSpan struct {
+	Name     string
+	TraceID  string
+	SpanID   string
+	ParentID string // 根 Span 为空
+	Start    time.Time
+	Duration time.Duration
+	Tags     map[string]interface{}
+	Err      error
+}
+
+// InMemory 把结束的 Span 保存在内存中, 主要用于测试
+type InMemory struct {
+	mu    sync.Mutex
+	spans []FinishedSpan
+}
+
+func NewInMemory() *InMemory {
+	return &InMemory{}
+}
+
+func (t *InMemory) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &memorySpan{
+		tracer: t,
+		data: FinishedSpan{
+			Name:   name,
+			SpanID: newID(8),
+			Start:  time.Now(),
+			Tags:   make(map[string]interface{}),
+		},
+	}
+	if parent, ok := ParentFromContext(ctx); ok {
+		span.data.TraceID = parent.TraceID
+		span.data.ParentID = parent.SpanID
+	} else {
+		span.data.TraceID = newID(16)
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+// Spans 返回按结束顺序排列的所有 Span
+func (t *InMemory) Spans() []FinishedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	spans := make([]FinishedSpan, len(t.spans))
+	copy(spans, t.spans)
+	return spans
+}
+
+// Reset 清空已记录的 Span
+func (t *InMemory) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = nil
+}
+
+func (t *InMemory) export(span FinishedSpan) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = append(t.spans, span)
+}
+
+type memorySpan struct {
+	tracer *InMemory
+	mu     sync.Mutex
+	data   FinishedSpan
+	ended  bool
+}
+
+func (s *memorySpan) Context() SpanContext {
+	return SpanContext{TraceID: s.data.TraceID, SpanID: s.data.SpanID}
+}
+
+func (s *memorySpan) SetTag(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Tags[key] = value
+}
+
+func (s *memorySpan) SetError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Err = err
+}
+
+func (s *memorySpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.Duration = time.Since(s.data.Start)
+	data := s.data
+	s.mu.Unlock()
+	s.tracer.export(data)
+}
+
+func newID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+)
+
+// 跨节点传递追踪信息的请求头
+const (
+	TraceIDHeader = "X-Geecache-Trace-Id"
+	SpanIDHeader  = "X-Geecache-Span-Id"
+)
+
+// SpanContext 标识一个 Span, 可以跨节点传递
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != "" && sc.SpanID != ""
+}
+
+// Span 是一次被追踪的操作
+type Span interface {
+	Context() SpanContext
+	SetTag(key string, value interface{})
+	SetError(err error)
+	End()
+}
+
+// Tracer 创建 Span, 如果 ctx 中已有 Span (或远程传来的 SpanContext), 新 Span 作为它的子节点
+// 返回的 ctx 中携带新创建的 Span
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// ContextWithSpan 返回携带 span 的 ctx, 供 Tracer 实现使用
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext 返回 ctx 中当前的 Span
+func SpanFromContext(ctx context.Context) (Span, bool) {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	return span, ok
+}
+
+// ParentFromContext 返回新 Span 的父节点: 优先使用本地的 Span, 其次是远程传来的 SpanContext
+func ParentFromContext(ctx context.Context) (SpanContext, bool) {
+	if span, ok := SpanFromContext(ctx); ok && span.Context().IsValid() {
+		return span.Context(), true
+	}
+	sc, ok := ctx.Value(remoteKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// Inject 将 ctx 中当前 Span 的标识写入请求头
+func Inject(ctx context.Context, header http.Header) {
+	if sc, ok := ParentFromContext(ctx); ok {
+		header.Set(TraceIDHeader, sc.TraceID)
+		header.Set(SpanIDHeader, sc.SpanID)
+	}
+}
+
+// Extract 从请求头中读取远程的 SpanContext 并放入 ctx
+func Extract(ctx context.Context, header http.Header) context.Context {
+	sc := SpanContext{TraceID: header.Get(TraceIDHeader), SpanID: header.Get(SpanIDHeader)}
+	if !sc.IsValid() {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// Noop 不记录任何信息的 Tracer, 是 Group 的默认值
+var Noop Tracer = noopTracer{}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) Context() SpanContext                 { return SpanContext{} }
+func (noopSpan) SetTag(key string, value interface{}) {}
+func (noopSpan) SetError(err error)                   {}
+func (noopSpan) End()                                 {}
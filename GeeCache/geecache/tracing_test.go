@@ -0,0 +1,63 @@
+package geecache
+
+import (
+	"context"
+	pb "geecache/geecachepb"
+	"geecache/tracing"
+	"net/http/httptest"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+// renamePeers 把请求转发给另一个名字的 Group, 用一个进程模拟两个节点
+type renamePeers struct {
+	getter *httpGetter
+	group  string
+}
+
+func (p *renamePeers) PickPeer(key string) (PeerGetter, bool) {
+	return p, true
+}
+
+func (p *renamePeers) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	return p.getter.Get(ctx, &pb.Request{Group: p.group, Key: in.GetKey(), AcceptCodecs: in.GetAcceptCodecs()}, out)
+}
+
+func TestTracing(t *testing.T) {
+	tracer := tracing.NewInMemory()
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	})
+	remote := mustNewGroup(t, "tracing-remote", 2<<10, getter)
+	remote.SetTracer(tracer)
+	srv := httptest.NewServer(NewHTTPPool(""))
+	defer srv.Close()
+
+	g := mustNewGroup(t, "tracing", 2<<10, getter)
+	g.SetTracer(tracer)
+	g.RegisterPeers(&renamePeers{getter: &httpGetter{baseURL: srv.URL + defaultBasePath}, group: "tracing-remote"})
+
+	g.Get("Tom")
+	spans := make(map[string][]tracing.FinishedSpan)
+	for _, span := range tracer.Spans() {
+		spans[span.Name] = append(spans[span.Name], span)
+	}
+
+	c.Convey("追踪信息跨节点传递", t, func() {
+		c.So(len(spans["geecache.Get"]), c.ShouldEqual, 2)
+		c.So(len(spans["cache.lookup"]), c.ShouldEqual, 2)
+		c.So(len(spans["singleflight.wait"]), c.ShouldEqual, 2)
+		c.So(len(spans["peer.fetch"]), c.ShouldEqual, 1)
+		c.So(len(spans["local.load"]), c.ShouldEqual, 1)
+
+		// 远程节点的 Span 结束得更早
+		remote, local := spans["geecache.Get"][0], spans["geecache.Get"][1]
+		fetch := spans["peer.fetch"][0]
+		c.So(local.ParentID, c.ShouldBeEmpty)
+		c.So(remote.TraceID, c.ShouldEqual, local.TraceID)
+		c.So(remote.ParentID, c.ShouldEqual, fetch.SpanID)
+		c.So(spans["local.load"][0].TraceID, c.ShouldEqual, local.TraceID)
+		c.So(spans["cache.lookup"][1].Tags["hit"], c.ShouldEqual, "none")
+	})
+}
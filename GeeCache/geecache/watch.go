@@ -0,0 +1,155 @@
+package geecache
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// EventType 缓存变更事件的类型
+type EventType int
+
+const (
+	EventSet        EventType = iota + 1 // key 被写入缓存
+	EventInvalidate                      // key 被主动失效
+	EventExpire                          // key 因容量不足被 LRU 淘汰
+)
+
+var eventTypeNames = map[EventType]string{
+	EventSet:        "set",
+	EventInvalidate: "invalidate",
+	EventExpire:     "expire",
+}
+
+func (t EventType) String() string {
+	if name, ok := eventTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+func (t EventType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *EventType) UnmarshalText(text []byte) error {
+	for k, v := range eventTypeNames {
+		if v == string(text) {
+			*t = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown event type: %s", text)
+}
+
+// Event 描述一次缓存变更, 事件只在发生变更的节点上产生, 不在节点之间传播
+// Version 在同一个 Watcher 内从 1 开始连续递增, 消费方发现跳变说明中间有事件因消费过慢被丢弃
+type Event struct {
+	Type    EventType `json:"type"`
+	Group   string    `json:"group"`
+	Key     string    `json:"key"`
+	Version uint64    `json:"version"`
+}
+
+const defaultWatchBuffer = 64
+
+// Watcher 订阅某个 Group 中指定 key 或前缀的变更事件
+type Watcher struct {
+	hub      *watchHub
+	keys     map[string]struct{}
+	prefixes []string
+	version  uint64
+	ch       chan Event
+	closed   bool
+}
+
+// Events 返回事件通道, Watcher 关闭后通道随之关闭
+func (w *Watcher) Events() <-chan Event {
+	return w.ch
+}
+
+// Close 取消订阅
+func (w *Watcher) Close() {
+	w.hub.remove(w)
+}
+
+// match 判断 key 是否在订阅范围内, 未指定 key 和前缀时订阅全部
+func (w *Watcher) match(key string) bool {
+	if len(w.keys) == 0 && len(w.prefixes) == 0 {
+		return true
+	}
+	if _, ok := w.keys[key]; ok {
+		return true
+	}
+	for _, prefix := range w.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchHub 管理一个 Group 上的所有 Watcher
+type watchHub struct {
+	mu       sync.Mutex
+	group    string
+	watchers map[*Watcher]struct{}
+}
+
+func (h *watchHub) add(keys, prefixes []string) *Watcher {
+	w := &Watcher{
+		hub:      h,
+		keys:     make(map[string]struct{}, len(keys)),
+		prefixes: prefixes,
+		ch:       make(chan Event, defaultWatchBuffer),
+	}
+	for _, key := range keys {
+		w.keys[key] = struct{}{}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.watchers == nil {
+		h.watchers = make(map[*Watcher]struct{})
+	}
+	h.watchers[w] = struct{}{}
+	return w
+}
+
+func (h *watchHub) remove(w *Watcher) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if w.closed {
+		return
+	}
+	w.closed = true
+	delete(h.watchers, w)
+	close(w.ch)
+}
+
+// closeAll 关闭所有 Watcher
+func (h *watchHub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for w := range h.watchers {
+		w.closed = true
+		close(w.ch)
+	}
+	h.watchers = nil
+}
+
+// publish 向所有匹配的 Watcher 投递事件, 通道已满时丢弃, 但仍然消耗一个版本号
+func (h *watchHub) publish(typ EventType, key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for w := range h.watchers {
+		if !w.match(key) {
+			continue
+		}
+		w.version++
+		select {
+		case w.ch <- Event{Type: typ, Group: h.group, Key: key, Version: w.version}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package geecache
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWatch(t *testing.T) {
+	g := mustNewGroup(t, "watch", 24, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+
+	all := g.Watch(nil, nil)
+	defer all.Close()
+	users := g.Watch([]string{"order:1"}, []string{"user:"})
+	defer users.Close()
+
+	g.Set("user:1", []byte("Tom"))
+	g.Set("order:1", []byte("1"))
+	g.Set("order:2", []byte("2")) // 容量不足, 挤出 user:1
+	g.Remove("order:2")
+
+	c.Convey("Watch 测试", t, func() {
+		tt := []struct {
+			name    string
+			watcher *Watcher
+			expect  []Event
+		}{
+			{name: "订阅全部", watcher: all, expect: []Event{
+				{Type: EventSet, Group: "watch", Key: "user:1", Version: 1},
+				{Type: EventSet, Group: "watch", Key: "order:1", Version: 2},
+				{Type: EventExpire, Group: "watch", Key: "user:1", Version: 3},
+				{Type: EventSet, Group: "watch", Key: "order:2", Version: 4},
+				{Type: EventInvalidate, Group: "watch", Key: "order:2", Version: 5},
+			}},
+			{name: "按 key 和前缀过滤", watcher: users, expect: []Event{
+				{Type: EventSet, Group: "watch", Key: "user:1", Version: 1},
+				{Type: EventSet, Group: "watch", Key: "order:1", Version: 2},
+				{Type: EventExpire, Group: "watch", Key: "user:1", Version: 3},
+			}},
+		}
+		for _, tc := range tt {
+			c.Convey(tc.name, func() {
+				var got []Event
+				for len(tc.watcher.Events()) > 0 {
+					got = append(got, <-tc.watcher.Events())
+				}
+				c.So(got, c.ShouldResemble, tc.expect)
+			})
+		}
+	})
+}
+
+func TestWatchGap(t *testing.T) {
+	g := mustNewGroup(t, "watch-gap", 0, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+
+	c.Convey("消费过慢时版本号出现跳变", t, func() {
+		w := g.Watch(nil, nil)
+		defer w.Close()
+		for i := 0; i < defaultWatchBuffer+1; i++ {
+			g.Set("key", []byte("v"))
+		}
+		for len(w.Events()) > 0 {
+			<-w.Events()
+		}
+		g.Set("key", []byte("v"))
+		c.So((<-w.Events()).Version, c.ShouldEqual, defaultWatchBuffer+2)
+	})
+}
+
+func TestWatchPeer(t *testing.T) {
+	g := mustNewGroup(t, "watch-http", 0, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+	pool := NewHTTPPool("")
+	srv := httptest.NewServer(pool)
+	defer srv.Close()
+
+	c.Convey("通过 HTTP 长连接订阅", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		events, err := WatchPeer(ctx, srv.URL, "watch-http", nil, []string{"user:"})
+		c.So(err, c.ShouldBeNil)
+
+		g.Set("order:1", []byte("1"))
+		g.Set("user:1", []byte("Tom"))
+		c.So(<-events, c.ShouldResemble, Event{Type: EventSet, Group: "watch-http", Key: "user:1", Version: 1})
+
+		_, err = WatchPeer(ctx, srv.URL, "no-such-group", nil, nil)
+		c.So(err, c.ShouldNotBeNil)
+
+		pool.CloseWatches()
+		_, ok := <-events
+		c.So(ok, c.ShouldBeFalse)
+	})
+}
@@ -0,0 +1,120 @@
+package geecache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"geecache/tracing"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// maxWriteBody 是接收其他节点转发的写入 (PUT 和 CompareAndSet) 时请求体的上限
+const maxWriteBody = 64 << 20
+
+// PeerWriter 是可以直接写入或删除 key 的节点, Put 和 Delete 会被路由到负责 key 的节点上
+type PeerWriter interface {
+	Put(ctx context.Context, group string, key string, value []byte) error
+	Delete(ctx context.Context, group string, key string) error
+}
+
+// Put 与 Set 相同, 但写入的是负责 key 的节点, 本节点上的热点副本会被删除
+func (g *Group) Put(ctx context.Context, key string, value []byte) error {
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	if err := g.checkSize(int64(len(value))); err != nil {
+		return err
+	}
+
+	if peer, ok := g.pickOwner(key); ok {
+		writer, ok := peer.(PeerWriter)
+		if !ok {
+			return fmt.Errorf("peer does not support Put")
+		}
+		err := writer.Put(ctx, g.name, key, value)
+		g.hotCache.remove(key)
+		return err
+	}
+	return g.Set(key, value)
+}
+
+// Delete 使负责 key 的节点以及本节点缓存中的 key 失效
+func (g *Group) Delete(ctx context.Context, key string) error {
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	// 本节点可能还留有节点变更前的条目或热点副本
+	g.Remove(key)
+
+	if peer, ok := g.pickOwner(key); ok {
+		writer, ok := peer.(PeerWriter)
+		if !ok {
+			return fmt.Errorf("peer does not support Delete")
+		}
+		return writer.Delete(ctx, g.name, key)
+	}
+	return nil
+}
+
+// serveWrite 处理其他节点转发过来的 PUT 和 DELETE, 总是在本节点执行, 不再转发
+func (p *HTTPPool) serveWrite(w http.ResponseWriter, r *http.Request, group *Group, key string) {
+	if r.Method == http.MethodDelete {
+		group.Remove(key)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxWriteBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	err = group.Set(key, body)
+	switch {
+	case errors.Is(err, ErrValueTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (h *httpGetter) Put(ctx context.Context, group string, key string, value []byte) error {
+	return h.write(ctx, http.MethodPut, group, key, value)
+}
+
+func (h *httpGetter) Delete(ctx context.Context, group string, key string) error {
+	return h.write(ctx, http.MethodDelete, group, key, nil)
+}
+
+func (h *httpGetter) write(ctx context.Context, method string, group string, key string, value []byte) error {
+	u := fmt.Sprintf("%v%v/%v", h.baseURL, url.QueryEscape(group), url.QueryEscape(key))
+	req, err := http.NewRequest(method, u, bytes.NewReader(value))
+	if err != nil {
+		return err
+	}
+	tracing.Inject(ctx, req.Header)
+	req.Header.Set(fromPeerHeader, "1")
+
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusRequestEntityTooLarge {
+		return fmt.Errorf("%s: %w", key, ErrValueTooLarge)
+	}
+	if res.StatusCode == http.StatusServiceUnavailable {
+		h.backoff(res.Header.Get("Retry-After"))
+		return fmt.Errorf("%s: %w", key, ErrPeerOverloaded)
+	}
+	if res.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("server returned: %v", res.Status)
+	}
+	return nil
+}
@@ -2,6 +2,9 @@ module example
 
 go 1.13
 
-require geecache v0.0.0
+require (
+	geecache v0.0.0
+	github.com/smartystreets/goconvey v1.8.0
+)
 
 replace geecache => ./geecache
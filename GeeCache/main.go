@@ -1,78 +1,150 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"geecache"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
-var db = map[string]string{
-	"Tom":  "630",
-	"Jack": "589",
-	"Sam":  "567",
-}
+const shutdownTimeout = 10 * time.Second
+
+// ready 为 1 表示节点可以对外服务
+var ready int32
 
-func createGroup() *geecache.Group {
-	return geecache.NewGroup("scores", 2<<10, geecache.GetterFunc(
-		func(key string) ([]byte, error) {
-			log.Println("[SlowDB] search key", key)
-			if v, ok := db[key]; ok {
-				return []byte(v), nil
+func createGroups(conf *Config) ([]*geecache.Group, error) {
+	gs := make([]*geecache.Group, 0, len(conf.Groups))
+	for _, gc := range conf.Groups {
+		getter, err := newGetter(gc.Source)
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %v", gc.Name, err)
+		}
+		g, err := geecache.NewGroup(gc.Name, gc.CacheBytes, getter, nil)
+		if err != nil {
+			return nil, err
+		}
+		g.SetChunkSize(gc.ChunkSize)
+		g.SetMaxValueSize(gc.MaxValueSize)
+		codec, _ := codecOf(gc.Compression)
+		g.SetCompression(codec, gc.CompressThreshold)
+		if gc.Tenants != nil {
+			sep := gc.Tenants.Separator
+			if sep == "" {
+				sep = ":"
+			}
+			if err := g.SetTenants(geecache.TenantPrefix(sep), gc.Tenants.Quotas); err != nil {
+				return nil, fmt.Errorf("group %s: %v", gc.Name, err)
 			}
-			return nil, fmt.Errorf("%s not exist", key)
-		}))
+		}
+		gs = append(gs, g)
+	}
+	return gs, nil
 }
 
-func startCacheServer(addr string, addrs []string, gee *geecache.Group) {
+func newCacheServer(addr string, addrs []string, limit LimitConfig, gs []*geecache.Group) (*http.Server, *geecache.HTTPPool, error) {
+	host, err := hostOf(addr)
+	if err != nil {
+		return nil, nil, err
+	}
 	peers := geecache.NewHTTPPool(addr)
+	timeout := time.Duration(limit.QueueTimeoutMS) * time.Millisecond
+	if err := peers.SetConcurrencyLimit(limit.MaxInflight, limit.MaxQueue, timeout); err != nil {
+		return nil, nil, fmt.Errorf("limit: %v", err)
+	}
 	peers.Set(addrs...)
-	gee.RegisterPeers(peers)
-	log.Println("geecache is running at", addr)
-	log.Fatal(http.ListenAndServe(addr[7:], peers))
-}
-
-func startAPIServer(apiAddr string, gee *geecache.Group) {
-	http.Handle("/api", http.HandlerFunc(
-		func(w http.ResponseWriter, r *http.Request) {
-			key := r.URL.Query().Get("key")
-			view, err := gee.Get(key)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			w.Header().Set("Content-Type", "application/octet-stream")
-			w.Write(view.ByteSlice())
-
-		}))
-	log.Println("fontend server is running at", apiAddr)
-	log.Fatal(http.ListenAndServe(apiAddr[7:], nil))
-
+	for _, g := range gs {
+		g.RegisterPeers(peers)
+	}
+	srv := &http.Server{Addr: host, Handler: peers}
+	// Shutdown 不会取消进行中请求的 context, 需要主动结束 _watch 长连接
+	srv.RegisterOnShutdown(peers.CloseWatches)
+	return srv, peers, nil
 }
 
 func main() {
-	var port int
+	var configPath, self string
 	var api bool
-	flag.IntVar(&port, "port", 8001, "Geecache server port")
+	flag.StringVar(&configPath, "config", "cluster.json", "Cluster config file")
+	flag.StringVar(&self, "self", "", "Address of this node, must be one of the peers in config")
 	flag.BoolVar(&api, "api", false, "Start a api server?")
 	flag.Parse()
 
-	apiAddr := "http://localhost:9999"
-	addrMap := map[int]string{
-		8001: "http://localhost:8001",
-		8002: "http://localhost:8002",
-		8003: "http://localhost:8003",
+	conf, err := loadConfig(configPath, api)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if self == "" {
+		self = conf.Peers[0]
+	}
+	if !conf.hasPeer(self) {
+		log.Fatalf("%s is not a peer in %s", self, configPath)
+	}
+
+	gs, err := createGroups(conf)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	var addrs []string
-	for _, v := range addrMap {
-		addrs = append(addrs, v)
+	servers := make([]*http.Server, 0, 2)
+	cacheServer, peers, err := newCacheServer(self, conf.Peers, conf.Limit, gs)
+	if err != nil {
+		log.Fatal(err)
 	}
+	servers = append(servers, cacheServer)
+	log.Println("geecache is running at", self)
 
-	gee := createGroup()
 	if api {
-		go startAPIServer(apiAddr, gee)
+		apiServer, err := newAPIServer(conf.API, conf.PreloadDir, peers)
+		if err != nil {
+			log.Fatal(err)
+		}
+		servers = append(servers, apiServer)
+		log.Println("fontend server is running at", conf.API)
+	}
+
+	// 所有端口都监听成功后才标记为就绪
+	listeners := make([]net.Listener, 0, len(servers))
+	for _, srv := range servers {
+		ln, err := net.Listen("tcp", srv.Addr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		listeners = append(listeners, ln)
+	}
+	errs := make(chan error, len(servers))
+	for i, srv := range servers {
+		go func(srv *http.Server, ln net.Listener) {
+			if err := srv.Serve(ln); err != http.ErrServerClosed {
+				errs <- err
+			}
+		}(srv, listeners[i])
+	}
+	atomic.StoreInt32(&ready, 1)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+	select {
+	case sig := <-stop:
+		log.Println("received", sig, "shutting down")
+	case err := <-errs:
+		log.Println("server error:", err)
+	}
+
+	// 先标记为未就绪, 让负载均衡摘除本节点, 再等待进行中的请求结束
+	atomic.StoreInt32(&ready, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Println("shutdown:", err)
+		}
 	}
-	startCacheServer(addrMap[port], addrs, gee)
 }
@@ -2,14 +2,17 @@
 trap "rm server;kill 0" EXIT
 
 go build -o server
-./server -port=8001 &
-./server -port=8002 &
-./server -port=8003 -api=1 &
+./server -config=cluster.json -self=http://localhost:8001 &
+./server -config=cluster.json -self=http://localhost:8002 &
+./server -config=cluster.json -self=http://localhost:8003 -api=1 &
 
 sleep 2
 echo ">>> start test"
-curl "http://localhost:9999/api?key=Tom" &
-curl "http://localhost:9999/api?key=Tom" &
-curl "http://localhost:9999/api?key=Tom" &
+curl "http://localhost:9999/readyz" && echo
+curl -X POST -d '{"group": "scores", "keys": ["Jack", "Sam"]}' "http://localhost:9999/admin/preload" && echo
+curl -X POST "http://localhost:9999/admin/flush/scores" && echo
+curl "http://localhost:9999/api/scores/Tom" &
+curl "http://localhost:9999/api/scores/Tom" &
+curl "http://localhost:9999/api/scores/Tom" &
 
-wait
\ No newline at end of file
+wait
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"geecache"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// newGetter 根据数据源配置创建 Getter
+func newGetter(src SourceConfig) (geecache.Getter, error) {
+	switch src.Type {
+	case "json":
+		return jsonSource(src.Path)
+	case "dir":
+		return dirSource(src.Path)
+	case "http":
+		return httpSource(src.URL)
+	default:
+		return nil, fmt.Errorf("unknown source type %q", src.Type)
+	}
+}
+
+// jsonSource 启动时读取一个 key 到 value 的 JSON 对象
+func jsonSource(path string) (geecache.Getter, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	db := make(map[string]string)
+	if err := json.Unmarshal(data, &db); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", path, err)
+	}
+
+	return geecache.GetterFunc(func(key string) ([]byte, error) {
+		log.Println("[JSONSource] search key", key)
+		if v, ok := db[key]; ok {
+			return []byte(v), nil
+		}
+		return nil, fmt.Errorf("%s: %w", key, geecache.ErrNotFound)
+	}), nil
+}
+
+// dirSource 以目录下的文件名作为 key, 文件内容作为 value
+func dirSource(dir string) (geecache.Getter, error) {
+	if info, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	return geecache.GetterFunc(func(key string) ([]byte, error) {
+		log.Println("[DirSource] search key", key)
+		// 不允许通过 key 访问目录之外的文件
+		if key != filepath.Base(key) || strings.HasPrefix(key, ".") {
+			return nil, fmt.Errorf("%s: %w", key, geecache.ErrNotFound)
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, key))
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s: %w", key, geecache.ErrNotFound)
+		}
+		return data, err
+	}), nil
+}
+
+// httpSource 请求 <baseURL>/<key> 获取 value
+func httpSource(baseURL string) (geecache.Getter, error) {
+	if _, err := url.Parse(baseURL); err != nil || baseURL == "" {
+		return nil, fmt.Errorf("invalid source url %q", baseURL)
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	return geecache.GetterFunc(func(key string) ([]byte, error) {
+		log.Println("[HTTPSource] search key", key)
+		res, err := client.Get(strings.TrimSuffix(baseURL, "/") + "/" + url.PathEscape(key))
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+
+		switch res.StatusCode {
+		case http.StatusOK:
+			return ioutil.ReadAll(res.Body)
+		case http.StatusNotFound:
+			return nil, fmt.Errorf("%s: %w", key, geecache.ErrNotFound)
+		default:
+			return nil, fmt.Errorf("source returned: %v", res.Status)
+		}
+	}), nil
+}
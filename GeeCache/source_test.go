@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"geecache"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJSONSource(t *testing.T) {
+	c.Convey("JSON 文件数据源", t, func() {
+		getter, err := newGetter(SourceConfig{Type: "json", Path: "data/scores.json"})
+		c.So(err, c.ShouldBeNil)
+		v, err := getter.Get("Tom")
+		c.So(err, c.ShouldBeNil)
+		c.So(string(v), c.ShouldEqual, "630")
+		_, err = getter.Get("Nobody")
+		c.So(errors.Is(err, geecache.ErrNotFound), c.ShouldBeTrue)
+
+		_, err = newGetter(SourceConfig{Type: "json", Path: "data/missing.json"})
+		c.So(err, c.ShouldNotBeNil)
+		_, err = newGetter(SourceConfig{Type: "csv"})
+		c.So(err, c.ShouldNotBeNil)
+	})
+}
+
+func TestDirSource(t *testing.T) {
+	root, err := ioutil.TempDir("", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "data")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "Tom"), []byte("630"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "secret"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Convey("目录数据源", t, func() {
+		getter, err := newGetter(SourceConfig{Type: "dir", Path: dir})
+		c.So(err, c.ShouldBeNil)
+		v, err := getter.Get("Tom")
+		c.So(err, c.ShouldBeNil)
+		c.So(string(v), c.ShouldEqual, "630")
+		for _, key := range []string{"Nobody", "../secret", ".", ".."} {
+			_, err = getter.Get(key)
+			c.So(errors.Is(err, geecache.ErrNotFound), c.ShouldBeTrue)
+		}
+
+		_, err = newGetter(SourceConfig{Type: "dir", Path: filepath.Join(dir, "Tom")})
+		c.So(err, c.ShouldNotBeNil)
+	})
+}
+
+func TestHTTPSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/scores/Tom":
+			w.Write([]byte("630"))
+		case "/scores/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c.Convey("HTTP 数据源", t, func() {
+		getter, err := newGetter(SourceConfig{Type: "http", URL: srv.URL + "/scores/"})
+		c.So(err, c.ShouldBeNil)
+		v, err := getter.Get("Tom")
+		c.So(err, c.ShouldBeNil)
+		c.So(string(v), c.ShouldEqual, "630")
+		_, err = getter.Get("Nobody")
+		c.So(errors.Is(err, geecache.ErrNotFound), c.ShouldBeTrue)
+		_, err = getter.Get("broken")
+		c.So(err, c.ShouldNotBeNil)
+		c.So(errors.Is(err, geecache.ErrNotFound), c.ShouldBeFalse)
+
+		_, err = newGetter(SourceConfig{Type: "http"})
+		c.So(err, c.ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,138 @@
+// ringstat 统计给定节点和虚拟节点数下, 一致性哈希的 key 分布和负载分布
+//
+//	go run ./tools/ringstat -peers 3 -replicas 50 -keys 100000 -inflight 64 -epsilon 0.25
+package main
+
+import (
+	"flag"
+	"fmt"
+	consistenthash "geecache/consistenhash"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// peerList 解析 -peers: 可以是节点数量, 也可以是逗号分隔的节点地址
+func peerList(spec string) []string {
+	if n, err := strconv.Atoi(spec); err == nil {
+		peers := make([]string, n)
+		for i := range peers {
+			peers[i] = fmt.Sprintf("http://localhost:%d", 8001+i)
+		}
+		return peers
+	}
+	return strings.Split(spec, ",")
+}
+
+// simulate 模拟 inflight 个并发请求, 返回每个节点被分配的请求数和观察到的最大并发数
+// 同一时刻最多有 inflight 个请求, 新请求到达前最早的请求结束
+func simulate(m *consistenthash.Map, keys []string, inflight int, epsilon float64) (assigned, peak map[string]int64) {
+	assigned = make(map[string]int64)
+	peak = make(map[string]int64)
+	loads := make(map[string]int64)
+	load := func(node string) int64 { return loads[node] }
+
+	window := make([]string, 0, inflight)
+	for _, key := range keys {
+		if len(window) == inflight {
+			loads[window[0]]--
+			window = window[1:]
+		}
+
+		var peer string
+		if epsilon > 0 {
+			peer = m.GetBounded(key, load, epsilon, "")
+		} else {
+			peer = m.Get(key)
+		}
+		assigned[peer]++
+		loads[peer]++
+		if loads[peer] > peak[peer] {
+			peak[peer] = loads[peer]
+		}
+
+		window = append(window, peer)
+	}
+	return
+}
+
+func maxOverMean(peers []string, counts map[string]int64) float64 {
+	var sum, max int64
+	for _, p := range peers {
+		sum += counts[p]
+		if counts[p] > max {
+			max = counts[p]
+		}
+	}
+	if sum == 0 {
+		return 0
+	}
+	return float64(max) / (float64(sum) / float64(len(peers)))
+}
+
+func main() {
+	var (
+		peerSpec string
+		replicas int
+		nkeys    int
+		inflight int
+		epsilon  float64
+		zipf     float64
+		seed     int64
+	)
+	flag.StringVar(&peerSpec, "peers", "3", "Number of peers, or comma separated peer addresses")
+	flag.IntVar(&replicas, "replicas", 50, "Virtual nodes per peer")
+	flag.IntVar(&nkeys, "keys", 100000, "Number of requests to simulate")
+	flag.IntVar(&inflight, "inflight", 64, "Concurrent in-flight requests")
+	flag.Float64Var(&epsilon, "epsilon", 0.25, "Bounded load factor, a peer may take (1+epsilon) times the average load")
+	flag.Float64Var(&zipf, "zipf", 0, "Zipf exponent (> 1) for skewed keys, 0 for uniform keys")
+	flag.Int64Var(&seed, "seed", 1, "Random seed")
+	flag.Parse()
+
+	peers := peerList(peerSpec)
+	m := consistenthash.New(replicas, nil)
+	m.Add(peers...)
+
+	r := rand.New(rand.NewSource(seed))
+	var z *rand.Zipf
+	if zipf > 1 {
+		z = rand.NewZipf(r, zipf, 1, uint64(nkeys))
+	}
+	keys := make([]string, nkeys)
+	for i := range keys {
+		if z != nil {
+			keys[i] = "key-" + strconv.FormatUint(z.Uint64(), 10)
+		} else {
+			keys[i] = "key-" + strconv.FormatInt(r.Int63(), 10)
+		}
+	}
+
+	// 不考虑请求, 只看不同 key 的归属
+	owned := make(map[string]int64)
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			owned[m.Get(key)]++
+		}
+	}
+	plain, plainPeak := simulate(m, keys, inflight, 0)
+	bounded, boundedPeak := simulate(m, keys, inflight, epsilon)
+
+	fmt.Printf("peers=%d replicas=%d requests=%d distinct=%d inflight=%d epsilon=%.2f\n\n",
+		len(peers), replicas, nkeys, len(seen), inflight, epsilon)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "peer\tkeys\tkeys%\trequests\tpeak\tbounded requests\tbounded peak\t")
+	for _, p := range peers {
+		fmt.Fprintf(w, "%s\t%d\t%.2f\t%d\t%d\t%d\t%d\t\n", p, owned[p],
+			float64(owned[p])/float64(len(seen))*100, plain[p], plainPeak[p], bounded[p], boundedPeak[p])
+	}
+	w.Flush()
+
+	ideal := int64(math.Ceil((1 + epsilon) * float64(inflight) / float64(len(peers))))
+	fmt.Printf("\nmax/mean keys: %.3f  max/mean requests: %.3f -> %.3f (bounded)  peak bound: %d\n",
+		maxOverMean(peers, owned), maxOverMean(peers, plain), maxOverMean(peers, bounded), ideal)
+}
@@ -18,32 +18,68 @@ const (
 	UPDATE
 	DELETE
 	COUNT
+	RETURNING
 )
 
 type Clause struct {
-	sql     map[Type]string
-	sqlVars map[Type][]interface{}
+	values map[Type][]interface{}
 }
 
+// Dialect 是生成 SQL 时需要的方言, 由 dialect.Dialect 实现
+type Dialect interface {
+	BindVar(n int) string           // 返回第 n 个 (从 1 开始) 占位符
+	Quote(identifier string) string // 给表名加上引号
+}
+
+// Set 设置 name 子句的参数, SQL 在 Build 时才生成
 func (c *Clause) Set(name Type, vars ...interface{}) {
-	if c.sql == nil {
-		c.sql = make(map[Type]string)
-		c.sqlVars = make(map[Type][]interface{})
+	if c.values == nil {
+		c.values = make(map[Type][]interface{})
 	}
-	sql, vars := generators[name](vars...)
-	c.sql[name] = sql
-	c.sqlVars[name] = vars
+	c.values[name] = vars
+}
+
+// Has 判断是否设置了 name 子句
+func (c *Clause) Has(name Type) bool {
+	_, ok := c.values[name]
+	return ok
 }
 
-func (c *Clause) Build(orders ...Type) (string, []interface{}) {
+// Build 按 orders 的顺序生成 SQL, 占位符使用方言 d 的形式连续编号
+// d 为 nil 时使用 ? 作为占位符, 表名不加引号
+func (c *Clause) Build(d Dialect, orders ...Type) (string, []interface{}) {
+	b := &builder{dialect: d}
 	var sqls []string
 	var vars []interface{}
 
 	for _, order := range orders {
-		if sql, ok := c.sql[order]; ok {
+		if values, ok := c.values[order]; ok {
+			sql, v := generators[order](b, values...)
 			sqls = append(sqls, sql)
-			vars = append(vars, c.sqlVars[order]...)
+			vars = append(vars, v...)
 		}
 	}
 	return strings.Join(sqls, " "), vars
 }
+
+// builder 在生成一条语句的过程中为占位符编号
+type builder struct {
+	dialect Dialect
+	n       int
+}
+
+// bindVar 返回下一个占位符
+func (b *builder) bindVar() string {
+	b.n++
+	if b.dialect == nil {
+		return "?"
+	}
+	return b.dialect.BindVar(b.n)
+}
+
+func (b *builder) quote(identifier string) string {
+	if b.dialect == nil {
+		return identifier
+	}
+	return b.dialect.Quote(identifier)
+}
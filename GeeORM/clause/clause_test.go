@@ -2,6 +2,7 @@ package clause
 
 import (
 	"github.com/smartystreets/goconvey/convey"
+	"strconv"
 	"testing"
 )
 
@@ -11,7 +12,7 @@ func TestClause_Build(t *testing.T) {
 	clause.Set(SELECT, "User", []string{"*"})
 	clause.Set(WHERE, "Name = ?", "Tom")
 	clause.Set(ORDERBY, "Age ASC")
-	sql, vars := clause.Build(SELECT, WHERE, ORDERBY, LIMIT)
+	sql, vars := clause.Build(nil, SELECT, WHERE, ORDERBY, LIMIT)
 	t.Log(sql, vars)
 
 	convey.Convey("Clause 测试", t, func() {
@@ -29,3 +30,21 @@ func TestClause_Build(t *testing.T) {
 	})
 
 }
+
+// numbered 使用 $n 作为占位符, 用双引号引用表名
+type numbered struct{}
+
+func (numbered) BindVar(n int) string           { return "$" + strconv.Itoa(n) }
+func (numbered) Quote(identifier string) string { return `"` + identifier + `"` }
+
+func TestClause_BuildDialect(t *testing.T) {
+	var clause Clause
+	clause.Set(UPDATE, "User", map[string]interface{}{"Name": "Tom", "Age": 18})
+	clause.Set(WHERE, "Name = ? AND Note <> '?'", "Sam")
+	sql, vars := clause.Build(numbered{}, UPDATE, WHERE)
+
+	convey.Convey("占位符在整条语句中连续编号", t, func() {
+		convey.So(sql, convey.ShouldEqual, `UPDATE "User" SET Age = $1, Name = $2 WHERE Name = $3 AND Note <> '?'`)
+		convey.So(vars, convey.ShouldResemble, []interface{}{18, "Tom", "Sam"})
+	})
+}
@@ -2,10 +2,12 @@ package clause
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
-type generator func(values ...interface{}) (string, []interface{})
+// generator 生成一个子句, 占位符由 b 生成, 保证整条语句中的编号连续
+type generator func(b *builder, values ...interface{}) (string, []interface{})
 
 var generators map[Type]generator
 
@@ -20,35 +22,32 @@ func init() {
 	generators[UPDATE] = _update
 	generators[DELETE] = _delete
 	generators[COUNT] = _count
+	generators[RETURNING] = _returning
 }
 
-func genBindVars(num int) string {
+func genBindVars(b *builder, num int) string {
 	var vars []string
 	for i := 0; i < num; i++ {
-		vars = append(vars, "?")
+		vars = append(vars, b.bindVar())
 	}
 	return strings.Join(vars, ", ")
 }
 
-func _insert(values ...interface{}) (string, []interface{}) {
+func _insert(b *builder, values ...interface{}) (string, []interface{}) {
 	// INSERT INTO $tableName ($fields)
-	tableName := values[0]
+	tableName := values[0].(string)
 	fields := strings.Join(values[1].([]string), ",")
-	return fmt.Sprintf("INSERT INTO %s (%v)", tableName, fields), []interface{}{}
+	return fmt.Sprintf("INSERT INTO %s (%v)", b.quote(tableName), fields), []interface{}{}
 }
 
-func _values(values ...interface{}) (string, []interface{}) {
+func _values(b *builder, values ...interface{}) (string, []interface{}) {
 	// VALUES ($v1), ($v2), ...
-	var bindStr string
 	var sql strings.Builder
 	var vars []interface{}
 	sql.WriteString("VALUES ")
 	for i, value := range values {
 		v := value.([]interface{})
-		if bindStr == "" {
-			bindStr = genBindVars(len(v))
-		}
-		sql.WriteString(fmt.Sprintf("(%v)", bindStr))
+		sql.WriteString(fmt.Sprintf("(%v)", genBindVars(b, len(v))))
 
 		if i+1 != len(values) {
 			sql.WriteString(", ")
@@ -58,45 +57,76 @@ func _values(values ...interface{}) (string, []interface{}) {
 	return sql.String(), vars
 }
 
-func _select(values ...interface{}) (string, []interface{}) {
+func _select(b *builder, values ...interface{}) (string, []interface{}) {
 	// SELECT $fields FROM $tableName
-	tableName := values[0]
+	tableName := values[0].(string)
 	fields := strings.Join(values[1].([]string), ",")
-	return fmt.Sprintf("SELECT %v FROM %s", fields, tableName), []interface{}{}
+	return fmt.Sprintf("SELECT %v FROM %s", fields, b.quote(tableName)), []interface{}{}
 }
 
-func _limit(values ...interface{}) (string, []interface{}) {
+func _limit(b *builder, values ...interface{}) (string, []interface{}) {
 	// LIMIT $num
-	return "LIMIT ?", values
+	return "LIMIT " + b.bindVar(), values
 }
 
-func _where(values ...interface{}) (string, []interface{}) {
+func _where(b *builder, values ...interface{}) (string, []interface{}) {
 	// WHERE $desc
-	desc, vars := values[0], values[1:]
-	return fmt.Sprintf("WHERE %s", desc), vars
+	desc, vars := values[0].(string), values[1:]
+	return fmt.Sprintf("WHERE %s", bindWhere(b, desc)), vars
 }
 
-func _orderBy(values ...interface{}) (string, []interface{}) {
+// bindWhere 把条件中的 ? 依次换成占位符, 引号内的 ? 保持不变
+func bindWhere(b *builder, desc string) string {
+	var sql strings.Builder
+	var quote rune
+	for _, r := range desc {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?':
+			sql.WriteString(b.bindVar())
+			continue
+		}
+		sql.WriteRune(r)
+	}
+	return sql.String()
+}
+
+func _orderBy(b *builder, values ...interface{}) (string, []interface{}) {
 	// ORDER BY %v
 	return fmt.Sprintf("ORDER BY %s", values[0]), []interface{}{}
 }
 
-func _update(values ...interface{}) (string, []interface{}) {
-	tableName := values[0]
+func _update(b *builder, values ...interface{}) (string, []interface{}) {
+	tableName := values[0].(string)
 	m := values[1].(map[string]interface{})
 	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	// 按字段名排序, 保证生成的 SQL 稳定
+	sort.Strings(keys)
 	var vars []interface{}
-	for k, v := range m {
-		keys = append(keys, k+" = ?")
-		vars = append(vars, v)
+	for i, k := range keys {
+		vars = append(vars, m[k])
+		keys[i] = k + " = " + b.bindVar()
 	}
-	return fmt.Sprintf("UPDATE %s SET %s", tableName, strings.Join(keys, ", ")), vars
+	return fmt.Sprintf("UPDATE %s SET %s", b.quote(tableName), strings.Join(keys, ", ")), vars
+}
+
+func _delete(b *builder, values ...interface{}) (string, []interface{}) {
+	return fmt.Sprintf("DELETE FROM %s", b.quote(values[0].(string))), []interface{}{}
 }
 
-func _delete(values ...interface{}) (string, []interface{}) {
-	return fmt.Sprintf("DELETE FROM %s", values[0]), []interface{}{}
+func _count(b *builder, values ...interface{}) (string, []interface{}) {
+	return _select(b, values[0], []string{"count(*)"})
 }
 
-func _count(values ...interface{}) (string, []interface{}) {
-	return _select(values[0], []string{"count(*)"})
+func _returning(b *builder, values ...interface{}) (string, []interface{}) {
+	// RETURNING $fields
+	return fmt.Sprintf("RETURNING %s", strings.Join(values[0].([]string), ",")), []interface{}{}
 }
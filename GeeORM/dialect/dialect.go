@@ -2,15 +2,34 @@
 // 描述: 实现对不同数据库的支持, 复用+解耦
 package dialect
 
-import "reflect"
+import (
+	"reflect"
+	"strings"
+)
 
 var dialectsMap = map[string]Dialect{}
 
+// DefaultVarcharSize 是字符串字段未指定 size 时 varchar 的长度
+const DefaultVarcharSize = 255
+
 type Dialect interface {
-	DataTypeOf(typ reflect.Value) string                    // 用于将 Go 语言的类型转换为数据库对应的数据类型
+	DataTypeOf(typ reflect.Value, size int) string          // 用于将 Go 语言的类型转换为数据库对应的数据类型, size 为 0 时使用默认长度
+	AutoIncrement(sqlType string) (string, string)          // 返回自增列的数据类型和约束条件
 	TableExistSQL(tableName string) (string, []interface{}) // 返回某个表是否存在的 SQL 语句,参数为表名
+	BindVar(n int) string                                   // 返回第 n 个 (从 1 开始) 占位符
+	Quote(identifier string) string                         // 给表名加上引号, 保留大小写并允许使用关键字
+	InsertIDMode() InsertIDMode                             // 返回插入后获取自增 ID 的方式
 }
 
+// InsertIDMode 描述插入后如何获取数据库生成的自增 ID
+type InsertIDMode int
+
+const (
+	LastIDOfBatch  InsertIDMode = iota // LastInsertId 返回批量插入中最后一行的 ID, 如 SQLite
+	FirstIDOfBatch                     // LastInsertId 返回批量插入中第一行的 ID, 如 MySQL
+	ReturningID                        // 使用 INSERT ... RETURNING 返回每一行的 ID, 如 PostgreSQL
+)
+
 func RegisterDialect(name string, dialect Dialect) {
 	dialectsMap[name] = dialect
 }
@@ -19,3 +38,8 @@ func GetDialect(name string) (dialect Dialect, ok bool) {
 	dialect, ok = dialectsMap[name]
 	return
 }
+
+// quote 用 q 包围 identifier, 其中的 q 写两次转义
+func quote(identifier string, q string) string {
+	return q + strings.ReplaceAll(identifier, q, q+q) + q
+}
@@ -0,0 +1,49 @@
+package dialect
+
+import (
+	"github.com/smartystreets/goconvey/convey"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDataTypeOf(t *testing.T) {
+	mysql, _ := GetDialect("mysql")
+	postgres, _ := GetDialect("postgres")
+	convey.Convey("类型映射测试", t, func() {
+		tt := []struct {
+			value    interface{}
+			mysql    string
+			postgres string
+		}{
+			{value: true, mysql: "boolean", postgres: "boolean"},
+			{value: int8(0), mysql: "tinyint", postgres: "smallint"},
+			{value: int32(0), mysql: "int", postgres: "integer"},
+			{value: 0, mysql: "bigint", postgres: "bigint"},
+			{value: uint8(0), mysql: "tinyint unsigned", postgres: "smallint"},
+			{value: uint32(0), mysql: "int unsigned", postgres: "bigint"},
+			{value: uint64(0), mysql: "bigint unsigned", postgres: "numeric(20)"},
+			{value: 0.5, mysql: "double", postgres: "double precision"},
+			{value: "", mysql: "varchar(255)", postgres: "varchar(255)"},
+			{value: []byte{}, mysql: "longblob", postgres: "bytea"},
+			{value: time.Time{}, mysql: "datetime(6)", postgres: "timestamp with time zone"},
+		}
+		for _, tc := range tt {
+			v := reflect.ValueOf(tc.value)
+			convey.So(mysql.DataTypeOf(v, 0), convey.ShouldEqual, tc.mysql)
+			convey.So(postgres.DataTypeOf(v, 0), convey.ShouldEqual, tc.postgres)
+		}
+		convey.So(mysql.DataTypeOf(reflect.ValueOf(""), 64), convey.ShouldEqual, "varchar(64)")
+		convey.So(postgres.DataTypeOf(reflect.ValueOf(""), 64), convey.ShouldEqual, "varchar(64)")
+	})
+}
+
+func TestQuote(t *testing.T) {
+	mysql, _ := GetDialect("mysql")
+	postgres, _ := GetDialect("postgres")
+	convey.Convey("标识符引号测试", t, func() {
+		convey.So(mysql.Quote("User"), convey.ShouldEqual, "`User`")
+		convey.So(postgres.Quote("User"), convey.ShouldEqual, `"User"`)
+		convey.So(postgres.Quote(`a"b`), convey.ShouldEqual, `"a""b"`)
+	})
+}
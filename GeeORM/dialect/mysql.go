@@ -0,0 +1,77 @@
+package dialect
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+type mysql struct{}
+
+var _ Dialect = (*mysql)(nil)
+
+func init() {
+	RegisterDialect("mysql", &mysql{})
+}
+
+func (m *mysql) DataTypeOf(typ reflect.Value, size int) string {
+	if size <= 0 {
+		size = DefaultVarcharSize
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int8:
+		return "tinyint"
+	case reflect.Int16:
+		return "smallint"
+	case reflect.Int32:
+		return "int"
+	case reflect.Int, reflect.Int64:
+		return "bigint"
+	case reflect.Uint8:
+		return "tinyint unsigned"
+	case reflect.Uint16:
+		return "smallint unsigned"
+	case reflect.Uint32:
+		return "int unsigned"
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return "bigint unsigned"
+	case reflect.Float32:
+		return "float"
+	case reflect.Float64:
+		return "double"
+	case reflect.String:
+		return fmt.Sprintf("varchar(%d)", size)
+	case reflect.Array, reflect.Slice:
+		return "longblob"
+	case reflect.Struct:
+		if _, ok := typ.Interface().(time.Time); ok {
+			// 保留微秒, 与 Go 的时间精度更接近
+			return "datetime(6)"
+		}
+	}
+	panic(fmt.Sprintf("invalid sql type %s (%s)", typ.Type().Name(), typ.Kind()))
+}
+
+func (m *mysql) AutoIncrement(sqlType string) (string, string) {
+	return sqlType, "AUTO_INCREMENT"
+}
+
+func (m *mysql) TableExistSQL(tableName string) (string, []interface{}) {
+	args := []interface{}{tableName}
+	return "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?", args
+}
+
+func (m *mysql) BindVar(n int) string {
+	return "?"
+}
+
+func (m *mysql) Quote(identifier string) string {
+	return quote(identifier, "`")
+}
+
+func (m *mysql) InsertIDMode() InsertIDMode {
+	// 多行 VALUES 属于 simple insert, InnoDB 一次分配连续的 ID
+	return FirstIDOfBatch
+}
@@ -0,0 +1,79 @@
+package dialect
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+type postgres struct{}
+
+var _ Dialect = (*postgres)(nil)
+
+func init() {
+	// lib/pq 注册为 postgres, jackc/pgx 注册为 pgx
+	RegisterDialect("postgres", &postgres{})
+	RegisterDialect("pgx", &postgres{})
+}
+
+func (p *postgres) DataTypeOf(typ reflect.Value, size int) string {
+	if size <= 0 {
+		size = DefaultVarcharSize
+	}
+	// PostgreSQL 没有无符号整数, 使用能容纳取值范围的更大类型
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return "smallint"
+	case reflect.Int32, reflect.Uint16:
+		return "integer"
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return "bigint"
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return "numeric(20)"
+	case reflect.Float32:
+		return "real"
+	case reflect.Float64:
+		return "double precision"
+	case reflect.String:
+		return fmt.Sprintf("varchar(%d)", size)
+	case reflect.Array, reflect.Slice:
+		return "bytea"
+	case reflect.Struct:
+		if _, ok := typ.Interface().(time.Time); ok {
+			return "timestamp with time zone"
+		}
+	}
+	panic(fmt.Sprintf("invalid sql type %s (%s)", typ.Type().Name(), typ.Kind()))
+}
+
+func (p *postgres) AutoIncrement(sqlType string) (string, string) {
+	// PostgreSQL 使用 serial 系列类型实现自增
+	switch sqlType {
+	case "smallint":
+		return "smallserial", ""
+	case "integer":
+		return "serial", ""
+	}
+	return "bigserial", ""
+}
+
+func (p *postgres) TableExistSQL(tableName string) (string, []interface{}) {
+	args := []interface{}{tableName}
+	return "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1", args
+}
+
+func (p *postgres) BindVar(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (p *postgres) Quote(identifier string) string {
+	return quote(identifier, `"`)
+}
+
+func (p *postgres) InsertIDMode() InsertIDMode {
+	// PostgreSQL 的驱动不支持 LastInsertId
+	return ReturningID
+}
@@ -14,7 +14,8 @@ func init() {
 	RegisterDialect("sqlite3", &sqlite3{})
 }
 
-func (s *sqlite3) DataTypeOf(typ reflect.Value) string {
+func (s *sqlite3) DataTypeOf(typ reflect.Value, size int) string {
+	// SQLite 不限制 text 的长度, 忽略 size
 	switch typ.Kind() {
 	case reflect.Bool:
 		return "bool"
@@ -37,7 +38,25 @@ func (s *sqlite3) DataTypeOf(typ reflect.Value) string {
 	panic(fmt.Sprintf("invalid sql type %s (%s)", typ.Type().Name(), typ.Kind()))
 }
 
+func (s *sqlite3) AutoIncrement(sqlType string) (string, string) {
+	// 只有 INTEGER PRIMARY KEY 才能使用 AUTOINCREMENT
+	return "integer", "AUTOINCREMENT"
+}
+
 func (s *sqlite3) TableExistSQL(tableName string) (string, []interface{}) {
 	args := []interface{}{tableName}
 	return "SELECT name FROM sqlite_master WHERE type='table' and name = ?", args
 }
+
+func (s *sqlite3) BindVar(n int) string {
+	return "?"
+}
+
+func (s *sqlite3) Quote(identifier string) string {
+	return quote(identifier, `"`)
+}
+
+func (s *sqlite3) InsertIDMode() InsertIDMode {
+	// 同一条语句插入的行的 ID 是连续的, RETURNING 返回的顺序不确定, 因此不使用
+	return LastIDOfBatch
+}
@@ -0,0 +1,84 @@
+package dialect
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+)
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// TypeMapper 可以由方言实现, 用于覆盖自定义类型的数据类型
+// typ 是实现了 sql.Scanner / driver.Valuer 的类型, 或者使用 JSON 序列化的字段类型
+// 返回 false 时使用 TypeOf 中的默认映射
+type TypeMapper interface {
+	CustomTypeOf(typ reflect.Type, size int, serializer string) (string, bool)
+}
+
+// IsValuer 判断 typ 是否通过 sql.Scanner 或 driver.Valuer 自行处理与数据库之间的转换
+func IsValuer(typ reflect.Type) bool {
+	return typ.Implements(valuerType) || reflect.PtrTo(typ).Implements(scannerType)
+}
+
+// TypeOf 返回 Go 类型 typ 在方言 d 中的数据类型, 是 DataTypeOf 的入口
+// 指针类型按指向的类型映射, 作为可以为 NULL 的列;
+// serializer 不为空时字段以文本形式存储, 未指定 size 时使用 text;
+// sql.Scanner / driver.Valuer 类型先交给方言的 TypeMapper,
+// 否则按 sql.NullString 这类 {值, Valid} 结构体中值的类型映射, 再否则按零值 Value() 的返回值映射, 都无法确定时作为字符串
+func TypeOf(d Dialect, typ reflect.Type, size int, serializer string) string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if serializer == "" && !IsValuer(typ) {
+		return d.DataTypeOf(reflect.New(typ).Elem(), size)
+	}
+	if m, ok := d.(TypeMapper); ok {
+		if sqlType, ok := m.CustomTypeOf(typ, size, serializer); ok {
+			return sqlType
+		}
+	}
+	if serializer != "" {
+		if size > 0 {
+			return d.DataTypeOf(reflect.ValueOf(""), size)
+		}
+		return "text"
+	}
+	if valueType, ok := nullValueType(typ); ok {
+		return TypeOf(d, valueType, size, "")
+	}
+	if v, ok := zeroValue(typ); ok && v != nil {
+		return d.DataTypeOf(reflect.ValueOf(v), size)
+	}
+	return d.DataTypeOf(reflect.ValueOf(""), size)
+}
+
+// nullValueType 识别 sql.NullString 这类由一个值和 Valid 组成的结构体, 返回值的类型
+func nullValueType(typ reflect.Type) (reflect.Type, bool) {
+	if typ.Kind() != reflect.Struct || typ.NumField() != 2 {
+		return nil, false
+	}
+	for i := 0; i < 2; i++ {
+		if f := typ.Field(i); f.Name == "Valid" && f.Type.Kind() == reflect.Bool {
+			return typ.Field(1 - i).Type, true
+		}
+	}
+	return nil, false
+}
+
+// zeroValue 调用 typ 零值的 Value 方法, 零值无法处理时返回 false
+func zeroValue(typ reflect.Type) (v driver.Value, ok bool) {
+	valuer, isValuer := reflect.New(typ).Interface().(driver.Valuer)
+	if !isValuer {
+		return nil, false
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+	v, err := valuer.Value()
+	return v, err == nil
+}
@@ -0,0 +1,52 @@
+package dialect
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"github.com/smartystreets/goconvey/convey"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Status 以字符串形式写入数据库
+type Status int
+
+func (s Status) Value() (driver.Value, error) {
+	return []string{"active", "disabled"}[s], nil
+}
+
+// Point 的零值无法确定数据类型
+type Point struct {
+	X, Y float64
+}
+
+func (p *Point) Scan(src interface{}) error { return nil }
+
+// jsonMapper 把 JSON 字段映射为 jsonb
+type jsonMapper struct {
+	Dialect
+}
+
+func (m jsonMapper) CustomTypeOf(typ reflect.Type, size int, serializer string) (string, bool) {
+	return "jsonb", serializer == "json"
+}
+
+func TestTypeOf(t *testing.T) {
+	sqlite3, _ := GetDialect("sqlite3")
+	postgres, _ := GetDialect("postgres")
+	typeOf := func(v interface{}) reflect.Type { return reflect.TypeOf(v) }
+	convey.Convey("自定义类型映射测试", t, func() {
+		convey.So(TypeOf(sqlite3, typeOf(new(string)), 0, ""), convey.ShouldEqual, "text")
+		convey.So(TypeOf(postgres, typeOf(new(int32)), 0, ""), convey.ShouldEqual, "integer")
+		convey.So(TypeOf(postgres, typeOf(sql.NullString{}), 32, ""), convey.ShouldEqual, "varchar(32)")
+		convey.So(TypeOf(postgres, typeOf(&sql.NullTime{}), 0, ""), convey.ShouldEqual, "timestamp with time zone")
+		convey.So(TypeOf(postgres, typeOf(Status(0)), 0, ""), convey.ShouldEqual, "varchar(255)")
+		convey.So(TypeOf(sqlite3, typeOf(Point{}), 0, ""), convey.ShouldEqual, "text")
+		convey.So(TypeOf(sqlite3, typeOf(time.Time{}), 0, ""), convey.ShouldEqual, "datetime")
+
+		convey.So(TypeOf(postgres, typeOf(map[string]int{}), 0, "json"), convey.ShouldEqual, "text")
+		convey.So(TypeOf(postgres, typeOf([]string{}), 1024, "json"), convey.ShouldEqual, "varchar(1024)")
+		convey.So(TypeOf(jsonMapper{postgres}, typeOf([]string{}), 0, "json"), convey.ShouldEqual, "jsonb")
+	})
+}
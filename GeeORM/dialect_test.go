@@ -0,0 +1,134 @@
+package GeeORM
+
+import (
+	"GeeORM/dialect"
+	"GeeORM/schema"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/smartystreets/goconvey/convey"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDriver 记录收到的 SQL 语句, 表总是存在, 查询整张表时返回 columns 中的列
+type fakeDriver struct {
+	mu      sync.Mutex
+	queries []string
+	columns []string
+}
+
+var fake = &fakeDriver{}
+
+func init() {
+	sql.Register("geeorm-fake", fake)
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return fakeConn{d}, nil
+}
+
+func (d *fakeDriver) record(query string) {
+	d.mu.Lock()
+	d.queries = append(d.queries, query)
+	d.mu.Unlock()
+}
+
+// take 返回并清空已记录的 SQL 语句
+func (d *fakeDriver) take() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	queries := d.queries
+	d.queries = nil
+	return queries
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.d.record(query)
+	return fakeStmt{c.d, query}, nil
+}
+
+func (c fakeConn) Close() error { return nil }
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	c.d.record("BEGIN")
+	return fakeTx{c.d}, nil
+}
+
+type fakeTx struct {
+	d *fakeDriver
+}
+
+func (tx fakeTx) Commit() error   { tx.d.record("COMMIT"); return nil }
+func (tx fakeTx) Rollback() error { tx.d.record("ROLLBACK"); return nil }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+func (st fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+func (st fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if strings.Contains(st.query, "information_schema") {
+		return &fakeRows{columns: []string{"table_name"}, values: args}, nil
+	}
+	return &fakeRows{columns: st.d.columns}, nil
+}
+
+// fakeRows 依次返回 values 中的值, 每行一列
+type fakeRows struct {
+	columns []string
+	values  []driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func TestEngine_MigratePostgres(t *testing.T) {
+	db, err := sql.Open("geeorm-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _ := dialect.GetDialect("postgres")
+	engine := &Engine{db: db, dialect: d, naming: schema.Naming{}}
+
+	// PostgreSQL 返回的列名是小写的
+	convey.Convey("PostgreSQL MIGRATE 测试", t, func() {
+		fake.take()
+		fake.columns = []string{"name", "age"}
+		convey.So(engine.Migrate(&User{}), convey.ShouldBeNil)
+		convey.So(fake.take(), convey.ShouldResemble, []string{
+			"BEGIN",
+			"SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1 ",
+			`SELECT * FROM "User" LIMIT 1; `,
+			"COMMIT",
+		})
+
+		fake.columns = []string{"name", "xxx"}
+		convey.So(engine.Migrate(&User{}), convey.ShouldBeNil)
+		convey.So(fake.take(), convey.ShouldResemble, []string{
+			"BEGIN",
+			"SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1 ",
+			`SELECT * FROM "User" LIMIT 1; `,
+			`ALTER TABLE "User" ADD COLUMN Age bigint; `,
+			`CREATE TABLE "tmp_User" AS SELECT Name, Age from "User"; DROP TABLE "User"; ALTER TABLE "tmp_User" RENAME TO "User"; `,
+			"COMMIT",
+		})
+	})
+}
@@ -8,6 +8,7 @@ package GeeORM
 import (
 	"GeeORM/dialect"
 	"GeeORM/log"
+	"GeeORM/schema"
 	"GeeORM/session"
 	"database/sql"
 	"fmt"
@@ -17,6 +18,7 @@ import (
 type Engine struct {
 	db      *sql.DB
 	dialect dialect.Dialect
+	naming  schema.NamingStrategy // 表名和列名的命名策略
 }
 
 func NewEngine(driver, source string) (*Engine, error) {
@@ -38,6 +40,7 @@ func NewEngine(driver, source string) (*Engine, error) {
 	e := &Engine{
 		db:      db,
 		dialect: d,
+		naming:  schema.Naming{},
 	}
 	log.Info("Connect database success")
 	return e, nil
@@ -50,8 +53,14 @@ func (e *Engine) Close() {
 	log.Info("Close database success")
 }
 
+// SetNamingStrategy 设置之后创建的 Session 使用的命名策略
+// 例如 schema.Naming{SnakeCase: true, PluralTable: true} 会把 UserInfo 映射为表 user_infos
+func (e *Engine) SetNamingStrategy(naming schema.NamingStrategy) {
+	e.naming = naming
+}
+
 func (e *Engine) NewSession() *session.Session {
-	return session.New(e.db, e.dialect)
+	return session.New(e.db, e.dialect).SetNamingStrategy(e.naming)
 }
 
 type TxFunc func(*session.Session) (interface{}, error)
@@ -80,14 +89,16 @@ func (e *Engine) Transaction(f TxFunc) (result interface{}, err error) {
 	return f(s)
 }
 
+// difference 返回 a 中有而 b 中没有的列名, 忽略大小写
+// PostgreSQL 会把没有加引号的列名转换为小写, 与 schema.GetField 一致
 func difference(a []string, b []string) (diff []string) {
 	mapB := make(map[string]bool)
 	for _, v := range b {
-		mapB[v] = true
+		mapB[strings.ToLower(v)] = true
 	}
 
 	for _, v := range a {
-		if _, ok := mapB[v]; !ok {
+		if _, ok := mapB[strings.ToLower(v)]; !ok {
 			diff = append(diff, v)
 		}
 	}
@@ -101,7 +112,8 @@ func (e *Engine) Migrate(value interface{}) error {
 			return nil, s.CreateTable()
 		}
 		table := s.RefTable()
-		rows, _ := s.Raw(fmt.Sprintf("SELECT * FROM %s LIMIT 1;", table.Name)).QueryRows()
+		name := e.dialect.Quote(table.Name)
+		rows, _ := s.Raw(fmt.Sprintf("SELECT * FROM %s LIMIT 1;", name)).QueryRows()
 		columns, _ := rows.Columns()
 		addCols := difference(table.FieldNames, columns)
 		delCols := difference(columns, table.FieldNames)
@@ -109,7 +121,7 @@ func (e *Engine) Migrate(value interface{}) error {
 
 		for _, col := range addCols {
 			f := table.GetField(col)
-			sqlStr := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table.Name, f.Name, f.Type)
+			sqlStr := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", name, f.Column, f.Type)
 			if _, err = s.Raw(sqlStr).Exec(); err != nil {
 				return
 			}
@@ -118,11 +130,11 @@ func (e *Engine) Migrate(value interface{}) error {
 		if len(delCols) == 0 {
 			return
 		}
-		tmp := "tmp_" + table.Name
+		tmp := e.dialect.Quote("tmp_" + table.Name)
 		fieldStr := strings.Join(table.FieldNames, ", ")
-		s.Raw(fmt.Sprintf("CREATE TABLE %s AS SELECT %s from %s;", tmp, fieldStr, table.Name))
-		s.Raw(fmt.Sprintf("DROP TABLE %s;", table.Name))
-		s.Raw(fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", tmp, table.Name))
+		s.Raw(fmt.Sprintf("CREATE TABLE %s AS SELECT %s from %s;", tmp, fieldStr, name))
+		s.Raw(fmt.Sprintf("DROP TABLE %s;", name))
+		s.Raw(fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", tmp, name))
 		_, err = s.Exec()
 		return
 	})
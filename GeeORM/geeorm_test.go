@@ -1,6 +1,7 @@
 package GeeORM
 
 import (
+	"GeeORM/schema"
 	"GeeORM/session"
 	"errors"
 	_ "github.com/mattn/go-sqlite3"
@@ -87,3 +88,32 @@ func TestEngine_Migrate(t *testing.T) {
 	})
 
 }
+
+type UserProfile struct {
+	UserName string `geeorm:"PRIMARY KEY"`
+	Age      int
+}
+
+func TestEngine_NamingStrategy(t *testing.T) {
+	engine := OpenDB(t)
+	defer engine.Close()
+	engine.SetNamingStrategy(schema.Naming{TablePrefix: "t_", SnakeCase: true, PluralTable: true})
+
+	s := engine.NewSession()
+	_, _ = s.Raw("DROP TABLE IF EXISTS t_user_profiles;").Exec()
+	_, _ = s.Raw("CREATE TABLE t_user_profiles(user_name text PRIMARY KEY, xxx integer);").Exec()
+
+	convey.Convey("命名策略测试", t, func() {
+		convey.So(engine.Migrate(&UserProfile{}), convey.ShouldBeNil)
+		rows, _ := s.Raw("SELECT * FROM t_user_profiles").QueryRows()
+		columns, _ := rows.Columns()
+		_ = rows.Close()
+		convey.So(columns, convey.ShouldResemble, []string{"user_name", "age"})
+
+		_, err := s.Insert(&UserProfile{UserName: "Tom", Age: 18})
+		convey.So(err, convey.ShouldBeNil)
+		var profiles []UserProfile
+		convey.So(s.Where("user_name = ?", "Tom").Find(&profiles), convey.ShouldBeNil)
+		convey.So(profiles, convey.ShouldResemble, []UserProfile{{UserName: "Tom", Age: 18}})
+	})
+}
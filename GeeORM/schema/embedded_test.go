@@ -0,0 +1,48 @@
+package schema
+
+import (
+	"github.com/smartystreets/goconvey/convey"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Model struct {
+	ID        int `geeorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type Author struct {
+	Name  string
+	Email string
+}
+
+type Book struct {
+	Model
+	*Author `geeorm:"embeddedPrefix:author_"`
+	Title   string
+	ID      int `geeorm:"column:ID;unique"` // 覆盖 Model 中的 ID
+}
+
+func TestParseEmbedded(t *testing.T) {
+	convey.Convey("嵌入字段解析测试", t, func() {
+		schema := Parse(&Book{}, TestDial, nil)
+		convey.So(schema.FieldNames, convey.ShouldResemble,
+			[]string{"ID", "CreatedAt", "UpdatedAt", "author_Name", "author_Email", "Title"})
+		convey.So(schema.GetField("ID").Index, convey.ShouldResemble, []int{3})
+		convey.So(schema.GetField("ID").Tag, convey.ShouldEqual, "UNIQUE")
+		convey.So(schema.GetField("author_Email").Index, convey.ShouldResemble, []int{1, 1})
+
+		// 嵌入的指针为 nil 时写入 NULL
+		book := &Book{Title: "Go", ID: 7}
+		values := schema.RecordValues(book)
+		convey.So(values[0], convey.ShouldEqual, 7)
+		convey.So(values[3], convey.ShouldBeNil)
+
+		dest := reflect.ValueOf(book).Elem()
+		*schema.GetField("author_Name").AddrOf(dest).(*string) = "Rob"
+		convey.So(book.Author.Name, convey.ShouldEqual, "Rob")
+		convey.So(schema.RecordValues(book)[3], convey.ShouldEqual, "Rob")
+	})
+}
@@ -0,0 +1,70 @@
+package schema
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NamingStrategy 决定 Go 的类型名和字段名映射到数据库中的表名和列名
+// 实现了 Tabler 的对象和带有 column 标签的字段不受影响
+type NamingStrategy interface {
+	TableName(typeName string) string   // 类型名 -> 表名
+	ColumnName(fieldName string) string // 字段名 -> 列名
+}
+
+// Naming 是 NamingStrategy 的默认实现, 零值保持 Go 中的名字不变
+type Naming struct {
+	TablePrefix string // 表名前缀
+	SnakeCase   bool   // 表名和列名使用蛇形命名, 如 UserID -> user_id
+	PluralTable bool   // 表名使用复数形式, 如 User -> users
+}
+
+var _ NamingStrategy = Naming{}
+
+func (n Naming) TableName(typeName string) string {
+	name := typeName
+	if n.SnakeCase {
+		name = toSnakeCase(name)
+	}
+	if n.PluralTable {
+		name = plural(name)
+	}
+	return n.TablePrefix + name
+}
+
+func (n Naming) ColumnName(fieldName string) string {
+	if n.SnakeCase {
+		return toSnakeCase(fieldName)
+	}
+	return fieldName
+}
+
+// toSnakeCase 在单词边界插入下划线, 连续的大写字母视为一个缩写, 如 HTTPServer -> http_server
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// plural 只处理英语中规则变化的名词
+func plural(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return name + "es"
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return name[:len(name)-1] + "ies"
+	}
+	return name + "s"
+}
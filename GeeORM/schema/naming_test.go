@@ -0,0 +1,32 @@
+package schema
+
+import (
+	"github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+type UserInfo struct {
+	UserID   int
+	HTTPAddr string
+	Nickname string `geeorm:"column:nick"`
+}
+
+func TestNaming(t *testing.T) {
+	convey.Convey("命名策略测试", t, func() {
+		convey.So(toSnakeCase("UserID"), convey.ShouldEqual, "user_id")
+		convey.So(toSnakeCase("HTTPServer"), convey.ShouldEqual, "http_server")
+		convey.So(toSnakeCase("Address2Line"), convey.ShouldEqual, "address2_line")
+		convey.So(plural("category"), convey.ShouldEqual, "categories")
+		convey.So(plural("box"), convey.ShouldEqual, "boxes")
+		convey.So(plural("day"), convey.ShouldEqual, "days")
+
+		schema := Parse(&UserInfo{}, TestDial, Naming{TablePrefix: "t_", SnakeCase: true, PluralTable: true})
+		convey.So(schema.Name, convey.ShouldEqual, "t_user_infos")
+		convey.So(schema.FieldNames, convey.ShouldResemble, []string{"user_id", "http_addr", "nick"})
+		convey.So(schema.GetField("user_id").Name, convey.ShouldEqual, "UserID")
+
+		// TableName 优先于命名策略
+		schema = Parse(&Account{}, TestDial, Naming{TablePrefix: "t_"})
+		convey.So(schema.Name, convey.ShouldEqual, "accounts")
+	})
+}
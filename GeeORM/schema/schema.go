@@ -4,62 +4,278 @@ package schema
 
 import (
 	"GeeORM/dialect"
+	"fmt"
 	"go/ast"
 	"reflect"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Field struct {
-	Name string // 字段名
-	Type string // 字段类型
-	Tag  string // 约束条件
+	Name          string // 字段名
+	Index         []int  // 字段在模型中的下标, 嵌入的字段有多级下标
+	Column        string // 列名
+	Type          string // 字段类型
+	Tag           string // 约束条件
+	Size          int    // 字段长度, 0 表示使用方言的默认长度
+	PrimaryKey    bool   // 是否为主键
+	AutoIncrement bool   // 是否自增
+	NotNull       bool   // 是否非空
+	Unique        bool   // 是否唯一
+	Default       string // 默认值, 为空表示没有默认值
+	Serializer    string // 序列化方式, 目前只支持 json
 }
 
 type Schema struct {
-	Model      interface{}       // 被映射的对象
-	Name       string            // 表名
-	Fields     []*Field          // 字段
-	FieldNames []string          // 字段名(列名)
-	fieldMap   map[string]*Field // 字段名和 Field 的映射关系
+	Model              interface{}       // 被映射的对象
+	Name               string            // 表名
+	Fields             []*Field          // 字段
+	FieldNames         []string          // 字段名(列名)
+	PrimaryField       *Field            // 第一个带有 primaryKey 标签的字段, 没有时为 nil
+	AutoIncrementField *Field            // 带有 autoIncrement 标签的字段, 没有时为 nil
+	fieldMap           map[string]*Field // 列名和 Field 的映射关系
 }
 
-func Parse(dest interface{}, d dialect.Dialect) *Schema {
+// Tabler 由需要自定义表名的对象实现
+type Tabler interface {
+	TableName() string
+}
+
+// Parse 解析 dest 的表结构, naming 为 nil 时使用 Go 中的名字
+func Parse(dest interface{}, d dialect.Dialect, naming NamingStrategy) *Schema {
+	if naming == nil {
+		naming = Naming{}
+	}
 	modelType := reflect.Indirect(reflect.ValueOf(dest)).Type()
 	schema := &Schema{
 		Model:    dest,
-		Name:     modelType.Name(),
+		Name:     naming.TableName(modelType.Name()),
 		fieldMap: make(map[string]*Field),
 	}
+	// 指针的方法集包含值的方法集, 两种接收者都能识别
+	if t, ok := reflect.New(modelType).Interface().(Tabler); ok {
+		schema.Name = t.TableName()
+	}
+
+	schema.parseFields(modelType, nil, "", d, naming)
+	for _, field := range schema.Fields {
+		if field.PrimaryKey && schema.PrimaryField == nil {
+			schema.PrimaryField = field
+		}
+		if field.AutoIncrement && schema.AutoIncrementField == nil {
+			schema.AutoIncrementField = field
+		}
+	}
+	return schema
+}
+
+// parseFields 解析 t 的字段, 匿名的结构体 (或结构体指针) 字段被展开, 其中的字段作为本表的列
+// index 是 t 在模型中的下标路径, prefix 是嵌入字段通过 embeddedPrefix 标签指定的列名前缀
+func (s *Schema) parseFields(t reflect.Type, index []int, prefix string, d dialect.Dialect, naming NamingStrategy) {
+	for i := 0; i < t.NumField(); i++ {
+		p := t.Field(i)
+		tag, _ := p.Tag.Lookup("geeorm")
+		if tag == "-" {
+			continue
+		}
+		fieldIndex := append(append([]int{}, index...), i)
 
-	for i := 0; i < modelType.NumField(); i++ {
-		p := modelType.Field(i)
+		if p.Anonymous && isEmbeddable(p) {
+			embedded := p.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			s.parseFields(embedded, fieldIndex, prefix+embeddedPrefix(tag), d, naming)
+			continue
+		}
 		if !p.Anonymous && ast.IsExported(p.Name) {
 			// 非匿名变量且是公开变量
-			field := &Field{
-				Name: p.Name,
-				Type: d.DataTypeOf(reflect.Indirect(reflect.New(p.Type))),
-			}
+			field := parseField(p, tag, d, naming)
+			field.Column = prefix + field.Column
+			field.Index = fieldIndex
+			s.addField(field)
+		}
+	}
+}
+
+// isEmbeddable 判断匿名字段是否需要展开, 未导出的结构体指针无法分配内存, 不展开
+func isEmbeddable(p reflect.StructField) bool {
+	t := p.Type
+	if t.Kind() == reflect.Ptr {
+		if !ast.IsExported(p.Name) {
+			return false
+		}
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{}) && !dialect.IsValuer(t)
+}
 
-			if v, ok := p.Tag.Lookup("geeorm"); ok {
-				field.Tag = v
+// addField 添加一个列, 列名重复时与 Go 的字段提升规则一致, 保留嵌套层次更浅的字段
+func (s *Schema) addField(field *Field) {
+	if old, ok := s.fieldMap[field.Column]; ok {
+		if len(field.Index) < len(old.Index) {
+			*old = *field
+		}
+		return
+	}
+	s.Fields = append(s.Fields, field)
+	s.FieldNames = append(s.FieldNames, field.Column)
+	s.fieldMap[field.Column] = field
+}
+
+func embeddedPrefix(tag string) string {
+	for _, part := range strings.Split(tag, ";") {
+		if i := strings.Index(part, ":"); i >= 0 && normalize(part[:i]) == "embeddedprefix" {
+			return strings.TrimSpace(part[i+1:])
+		}
+	}
+	return ""
+}
+
+// parseField 解析形如 `geeorm:"column:name;size:64;primaryKey;notNull"` 的标签
+// 键不区分大小写并忽略空格和下划线, 因此 PRIMARY KEY 与 primaryKey 等价
+// 无法识别的部分原样追加到约束条件中, 没有 column 标签时按命名策略生成列名
+func parseField(p reflect.StructField, tag string, d dialect.Dialect, naming NamingStrategy) *Field {
+	field := &Field{Name: p.Name, Column: naming.ColumnName(p.Name)}
+	var sqlType string
+	var extra []string
+	for _, part := range strings.Split(tag, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value := part, ""
+		if i := strings.Index(part, ":"); i >= 0 {
+			key, value = part[:i], strings.TrimSpace(part[i+1:])
+		}
+		switch normalize(key) {
+		case "column":
+			field.Column = value
+		case "type":
+			sqlType = value
+		case "size":
+			field.Size, _ = strconv.Atoi(value)
+		case "primarykey":
+			field.PrimaryKey = true
+		case "autoincrement":
+			field.AutoIncrement = true
+		case "notnull":
+			field.NotNull = true
+		case "unique":
+			field.Unique = true
+		case "default":
+			field.Default = value
+		case "serializer":
+			field.Serializer = strings.ToLower(value)
+			if field.Serializer != "json" {
+				panic(fmt.Sprintf("unknown serializer %s of field %s", value, p.Name))
 			}
+		case "embeddedprefix":
+			// 只对嵌入字段有效
+		default:
+			extra = append(extra, part)
+		}
+	}
 
-			schema.Fields = append(schema.Fields, field)
-			schema.FieldNames = append(schema.FieldNames, field.Name)
-			schema.fieldMap[field.Name] = field
+	if sqlType == "" {
+		sqlType = dialect.TypeOf(d, p.Type, field.Size, field.Serializer)
+	}
+	var constraints []string
+	if field.PrimaryKey {
+		constraints = append(constraints, "PRIMARY KEY")
+	}
+	if field.AutoIncrement {
+		var c string
+		sqlType, c = d.AutoIncrement(sqlType)
+		if c != "" {
+			constraints = append(constraints, c)
 		}
 	}
-	return schema
+	if field.NotNull {
+		constraints = append(constraints, "NOT NULL")
+	}
+	if field.Unique {
+		constraints = append(constraints, "UNIQUE")
+	}
+	if field.Default != "" {
+		constraints = append(constraints, "DEFAULT "+field.Default)
+	}
+	field.Type = sqlType
+	field.Tag = strings.Join(append(constraints, extra...), " ")
+	return field
+}
+
+func normalize(key string) string {
+	return strings.NewReplacer(" ", "", "_", "").Replace(strings.ToLower(key))
 }
 
+// GetField 根据列名返回对应的 Field, 没有时忽略大小写再查找一次
+// PostgreSQL 会把没有加引号的列名转换为小写, 查询结果中的列名与 Field.Column 的大小写可能不同
 func (s *Schema) GetField(name string) *Field {
-	return s.fieldMap[name]
+	if field, ok := s.fieldMap[name]; ok {
+		return field
+	}
+	for _, field := range s.Fields {
+		if strings.EqualFold(field.Column, name) {
+			return field
+		}
+	}
+	return nil
 }
 
 func (s *Schema) RecordValues(dest interface{}) []interface{} {
+	return s.RecordValuesOf(dest, s.Fields)
+}
+
+// RecordValuesOf 按 fields 的顺序返回 dest 中这些字段的值
+func (s *Schema) RecordValuesOf(dest interface{}, fields []*Field) []interface{} {
 	destValue := reflect.Indirect(reflect.ValueOf(dest))
 	var fieldValues []interface{}
-	for _, field := range s.Fields {
-		fieldValues = append(fieldValues, destValue.FieldByName(field.Name).Interface())
+	for _, field := range fields {
+		// 嵌入的结构体指针为 nil 时写入 NULL
+		var value interface{}
+		if v := field.ValueOf(destValue); v.IsValid() {
+			value = v.Interface()
+			if field.Serializer == "json" {
+				value = jsonValue{v}
+			}
+		}
+		fieldValues = append(fieldValues, value)
 	}
 	return fieldValues
 }
+
+// ValueOf 返回 dest 中该字段的值, 路径上有 nil 指针时返回无效的 reflect.Value
+func (f *Field) ValueOf(dest reflect.Value) reflect.Value {
+	v := dest
+	for i, x := range f.Index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// AddrOf 返回 dest 中该字段的指针, 用于 Scan, 路径上的 nil 指针会被分配内存
+// 指针字段可以接收 NULL, 使用 JSON 序列化的字段返回负责解码的 sql.Scanner
+func (f *Field) AddrOf(dest reflect.Value) interface{} {
+	v := dest
+	for i, x := range f.Index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	if f.Serializer == "json" {
+		return &jsonScanner{v}
+	}
+	return v.Addr().Interface()
+}
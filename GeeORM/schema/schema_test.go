@@ -14,7 +14,7 @@ type User struct {
 var TestDial, _ = dialect.GetDialect("sqlite3")
 
 func TestParse(t *testing.T) {
-	schema := Parse(&User{}, TestDial)
+	schema := Parse(&User{}, TestDial, nil)
 	convey.Convey("解析测试", t, func() {
 		convey.Convey("表名解析", func() {
 			convey.So(schema.Name, convey.ShouldEqual, "User")
@@ -29,3 +29,44 @@ func TestParse(t *testing.T) {
 	})
 
 }
+
+type Account struct {
+	ID       int    `geeorm:"column:id;primaryKey;autoIncrement"`
+	Email    string `geeorm:"column:email;size:64;notNull;unique"`
+	Nickname string `geeorm:"type:varchar(32);default:'guest'"`
+	Password string `geeorm:"-"`
+	Balance  int    `geeorm:"CHECK (Balance >= 0)"`
+}
+
+func (a *Account) TableName() string {
+	return "accounts"
+}
+
+func TestParseTags(t *testing.T) {
+	convey.Convey("标签解析测试", t, func() {
+		schema := Parse(&Account{}, TestDial, nil)
+		convey.So(schema.Name, convey.ShouldEqual, "accounts")
+		convey.So(schema.FieldNames, convey.ShouldResemble, []string{"id", "email", "Nickname", "Balance"})
+
+		id := schema.GetField("id")
+		convey.So(id.Name, convey.ShouldEqual, "ID")
+		convey.So(id.Type, convey.ShouldEqual, "integer")
+		convey.So(id.Tag, convey.ShouldEqual, "PRIMARY KEY AUTOINCREMENT")
+		convey.So(schema.PrimaryField, convey.ShouldEqual, id)
+		convey.So(schema.AutoIncrementField, convey.ShouldEqual, id)
+		convey.So(schema.GetField("email").Tag, convey.ShouldEqual, "NOT NULL UNIQUE")
+		convey.So(schema.GetField("Nickname").Type, convey.ShouldEqual, "varchar(32)")
+		convey.So(schema.GetField("Nickname").Tag, convey.ShouldEqual, "DEFAULT 'guest'")
+		convey.So(schema.GetField("Balance").Tag, convey.ShouldEqual, "CHECK (Balance >= 0)")
+
+		mysql, _ := dialect.GetDialect("mysql")
+		schema = Parse(&Account{}, mysql, nil)
+		convey.So(schema.GetField("id").Tag, convey.ShouldEqual, "PRIMARY KEY AUTO_INCREMENT")
+		convey.So(schema.GetField("email").Type, convey.ShouldEqual, "varchar(64)")
+
+		postgres, _ := dialect.GetDialect("postgres")
+		schema = Parse(&Account{}, postgres, nil)
+		convey.So(schema.GetField("id").Type, convey.ShouldEqual, "bigserial")
+		convey.So(schema.GetField("id").Tag, convey.ShouldEqual, "PRIMARY KEY")
+	})
+}
@@ -0,0 +1,50 @@
+package schema
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// jsonValue 在写入时把字段编码为 JSON 文本, nil 的 map, 切片和指针写入 NULL
+type jsonValue struct {
+	v reflect.Value
+}
+
+func (j jsonValue) Value() (driver.Value, error) {
+	switch j.v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr, reflect.Interface:
+		if j.v.IsNil() {
+			return nil, nil
+		}
+	}
+	b, err := json.Marshal(j.v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// jsonScanner 在读取时把 JSON 文本解码到字段中, NULL 解码为零值
+type jsonScanner struct {
+	v reflect.Value
+}
+
+func (j *jsonScanner) Scan(src interface{}) error {
+	var b []byte
+	switch src := src.(type) {
+	case nil:
+		j.v.Set(reflect.Zero(j.v.Type()))
+		return nil
+	case []byte:
+		b = src
+	case string:
+		b = []byte(src)
+	default:
+		return fmt.Errorf("cannot decode %T as json", src)
+	}
+	// 先清空字段, 避免 map 中残留旧的键
+	j.v.Set(reflect.Zero(j.v.Type()))
+	return json.Unmarshal(b, j.v.Addr().Interface())
+}
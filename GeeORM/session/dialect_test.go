@@ -0,0 +1,233 @@
+package session
+
+import (
+	"GeeORM/dialect"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/smartystreets/goconvey/convey"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDriver 只记录收到的 SQL 语句, 用于检查不同方言生成的 SQL
+type fakeDriver struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+var fake = &fakeDriver{}
+
+func init() {
+	sql.Register("geeorm-fake", fake)
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return fakeConn{d}, nil
+}
+
+// take 返回并清空已记录的 SQL 语句
+func (d *fakeDriver) take() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	queries := d.queries
+	d.queries = nil
+	return queries
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (d *fakeDriver) record(query string) {
+	d.mu.Lock()
+	d.queries = append(d.queries, query)
+	d.mu.Unlock()
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.d.record(query)
+	return fakeStmt{query}, nil
+}
+
+func (c fakeConn) Close() error { return nil }
+
+// Begin 以及事务的提交和回滚也会被记录
+func (c fakeConn) Begin() (driver.Tx, error) {
+	c.d.record("BEGIN")
+	return fakeTx{c.d}, nil
+}
+
+type fakeTx struct {
+	d *fakeDriver
+}
+
+func (tx fakeTx) Commit() error   { tx.d.record("COMMIT"); return nil }
+func (tx fakeTx) Rollback() error { tx.d.record("ROLLBACK"); return nil }
+
+// fakeStmt 把 VALUES 中的每一行视为插入成功, 自增 ID 从 fakeFirstID 开始
+type fakeStmt struct {
+	query string
+}
+
+const fakeFirstID = 100
+
+func (st fakeStmt) rows() int64 {
+	if !strings.HasPrefix(st.query, "INSERT") {
+		return 0
+	}
+	return int64(strings.Count(st.query, "), (") + 1)
+}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+func (st fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	// 与 MySQL 一致, LastInsertId 返回第一行的 ID
+	return fakeResult{rows: st.rows()}, nil
+}
+func (st fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if strings.Contains(st.query, "RETURNING") {
+		return &fakeRows{columns: []string{"id"}, rows: st.rows()}, nil
+	}
+	return &fakeRows{columns: []string{"Name", "Age"}}, nil
+}
+
+type fakeResult struct {
+	rows int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) { return fakeFirstID, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return r.rows, nil }
+
+// fakeRows 返回 rows 行递增的 ID, rows 为 0 时是没有数据的结果集
+type fakeRows struct {
+	columns []string
+	rows    int64
+	next    int64
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= r.rows {
+		return io.EOF
+	}
+	dest[0] = fakeFirstID + r.next
+	r.next++
+	return nil
+}
+
+func newFakeSession(t *testing.T, name string) *Session {
+	db, err := sql.Open("geeorm-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _ := dialect.GetDialect(name)
+	fake.take()
+	return New(db, d).Model(&User{})
+}
+
+func TestDialect_GoldenSQL(t *testing.T) {
+	convey.Convey("方言 SQL 测试", t, func() {
+		tt := []struct {
+			dialect string
+			golden  []string
+		}{
+			{
+				dialect: "mysql",
+				golden: []string{
+					"CREATE TABLE `User` (Name varchar(255) PRIMARY KEY,Age bigint ); ",
+					"SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ? ",
+					"INSERT INTO `User` (Name,Age) VALUES (?, ?), (?, ?) ",
+					"SELECT Name,Age FROM `User` WHERE Age > ? ORDER BY Age DESC LIMIT ? ",
+					"UPDATE `User` SET Age = ?, Name = ? WHERE Name = ? ",
+					"DELETE FROM `User` WHERE Name = ? ",
+					"SELECT count(*) FROM `User` WHERE Age = ? ",
+				},
+			},
+			{
+				dialect: "postgres",
+				golden: []string{
+					"CREATE TABLE \"User\" (Name varchar(255) PRIMARY KEY,Age bigint ); ",
+					"SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1 ",
+					"INSERT INTO \"User\" (Name,Age) VALUES ($1, $2), ($3, $4) ",
+					"SELECT Name,Age FROM \"User\" WHERE Age > $1 ORDER BY Age DESC LIMIT $2 ",
+					"UPDATE \"User\" SET Age = $1, Name = $2 WHERE Name = $3 ",
+					"DELETE FROM \"User\" WHERE Name = $1 ",
+					"SELECT count(*) FROM \"User\" WHERE Age = $1 ",
+				},
+			},
+		}
+		for _, tc := range tt {
+			convey.Convey(tc.dialect, func() {
+				s := newFakeSession(t, tc.dialect)
+				_ = s.CreateTable()
+				s.HasTable()
+				_, _ = s.Insert(&User{"Tom", 18}, &User{"Sam", 25})
+				var users []User
+				_ = s.Where("Age > ?", 20).OrderBy("Age DESC").Limit(3).Find(&users)
+				_, _ = s.Where("Name = ?", "Tom").Update("Name", "Tom", "Age", 30)
+				_, _ = s.Where("Name = ?", "Tom").Delete()
+				_, _ = s.Where("Age = ?", 18).Count()
+				convey.So(fake.take(), convey.ShouldResemble, tc.golden)
+			})
+		}
+	})
+}
+
+type Ticket struct {
+	ID    int `geeorm:"primaryKey;autoIncrement"`
+	Title string
+}
+
+func TestDialect_InsertID(t *testing.T) {
+	// 指定了 ID 和需要生成 ID 的记录分成两条语句, 在同一个事务中插入
+	convey.Convey("自增 ID 写回测试", t, func() {
+		tt := []struct {
+			dialect string
+			golden  []string
+		}{
+			{
+				dialect: "mysql",
+				golden: []string{
+					"BEGIN",
+					"INSERT INTO `Ticket` (ID,Title) VALUES (?, ?) ",
+					"INSERT INTO `Ticket` (Title) VALUES (?), (?) ",
+					"COMMIT",
+				},
+			},
+			{
+				dialect: "postgres",
+				golden: []string{
+					"BEGIN",
+					"INSERT INTO \"Ticket\" (ID,Title) VALUES ($1, $2) ",
+					"INSERT INTO \"Ticket\" (Title) VALUES ($1), ($2) RETURNING ID ",
+					"COMMIT",
+				},
+			},
+		}
+		for _, tc := range tt {
+			convey.Convey(tc.dialect, func() {
+				s := newFakeSession(t, tc.dialect)
+				tickets := []*Ticket{{Title: "a"}, {ID: 7, Title: "b"}, {Title: "c"}}
+				affected, err := s.Insert(tickets[0], tickets[1], tickets[2])
+				convey.So(err, convey.ShouldBeNil)
+				convey.So(affected, convey.ShouldEqual, 3)
+				convey.So(fake.take(), convey.ShouldResemble, tc.golden)
+				convey.So([]int{tickets[0].ID, tickets[1].ID, tickets[2].ID}, convey.ShouldResemble, []int{100, 7, 101})
+			})
+		}
+	})
+}
+
+func TestDialect_RawSQL(t *testing.T) {
+	// Raw 中的 SQL 原样发送, JSONB 的 ? 运算符不会被当作占位符
+	convey.Convey("原始 SQL 不做改写", t, func() {
+		s := newFakeSession(t, "postgres")
+		_, _ = s.Raw(`SELECT Name FROM "User" WHERE Tags ? 'admin' AND Age > $1`, 18).Exec()
+		convey.So(fake.take(), convey.ShouldResemble, []string{
+			`SELECT Name FROM "User" WHERE Tags ? 'admin' AND Age > $1 `,
+		})
+	})
+}
@@ -19,9 +19,11 @@ const (
 
 // CallMethod calls the registered hooks
 func (s *Session) CallMethod(method string, value interface{}) {
-	fm := reflect.ValueOf(s.RefTable().Model).MethodByName(method)
+	var fm reflect.Value
 	if value != nil {
 		fm = reflect.ValueOf(value).MethodByName(method)
+	} else if s.refTable != nil {
+		fm = reflect.ValueOf(s.refTable.Model).MethodByName(method)
 	}
 	param := []reflect.Value{reflect.ValueOf(s)}
 	if fm.IsValid() {
@@ -0,0 +1,95 @@
+package session
+
+import (
+	"GeeORM/schema"
+	"reflect"
+)
+
+// Save 保存 value, 主键为零值或者记录不存在时插入, 否则更新除主键外的所有列
+func (s *Session) Save(value interface{}) (int64, error) {
+	table := s.Model(value).RefTable()
+	pk := table.PrimaryField
+	if pk == nil {
+		return 0, ErrMissingPrimaryKey
+	}
+	if isZero(pk, value) {
+		return s.Insert(value)
+	}
+	count, err := s.wherePrimaryKey(value).Count()
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return s.Insert(value)
+	}
+
+	var fields []*schema.Field
+	for _, field := range table.Fields {
+		if field != pk && field != table.AutoIncrementField {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return 0, nil
+	}
+	return s.updateModel(value, fields)
+}
+
+// Updates 按主键更新 value 中不为零值的字段, 主键为零值时返回 ErrMissingPrimaryKey
+func (s *Session) Updates(value interface{}) (int64, error) {
+	table := s.Model(value).RefTable()
+	pk := table.PrimaryField
+	if pk == nil || isZero(pk, value) {
+		return 0, ErrMissingPrimaryKey
+	}
+
+	var fields []*schema.Field
+	for _, field := range table.Fields {
+		if field != pk && !isZero(field, value) {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return 0, nil
+	}
+	return s.updateModel(value, fields)
+}
+
+// DeleteModel 按主键删除 value 对应的记录, 之前设置的 Where 会被忽略
+func (s *Session) DeleteModel(value interface{}) (int64, error) {
+	table := s.Model(value).RefTable()
+	if pk := table.PrimaryField; pk == nil || isZero(pk, value) {
+		return 0, ErrMissingPrimaryKey
+	}
+
+	s.CallMethod(BeforeDelete, value)
+	affected, err := s.wherePrimaryKey(value).delete()
+	if err != nil {
+		return 0, err
+	}
+	s.CallMethod(AfterDelete, value)
+	return affected, nil
+}
+
+// updateModel 按主键把 value 中 fields 的值写入数据库
+func (s *Session) updateModel(value interface{}, fields []*schema.Field) (int64, error) {
+	table := s.RefTable()
+	m := make(map[string]interface{}, len(fields))
+	for i, v := range table.RecordValuesOf(value, fields) {
+		m[fields[i].Column] = v
+	}
+
+	s.CallMethod(BeforeUpdate, value)
+	affected, err := s.wherePrimaryKey(value).update(m)
+	if err != nil {
+		return 0, err
+	}
+	s.CallMethod(AfterUpdate, value)
+	return affected, nil
+}
+
+func (s *Session) wherePrimaryKey(value interface{}) *Session {
+	pk := s.RefTable().PrimaryField
+	v := pk.ValueOf(reflect.Indirect(reflect.ValueOf(value)))
+	return s.Where(pk.Column+" = ?", v.Interface())
+}
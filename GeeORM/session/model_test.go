@@ -0,0 +1,100 @@
+package session
+
+import (
+	"github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+type Note struct {
+	ID      int `geeorm:"primaryKey;autoIncrement"`
+	Title   string
+	Content string
+}
+
+func testNoteInit(t *testing.T) *Session {
+	t.Helper()
+	s := NewSession().Model(&Note{})
+	if err := s.DropTable(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateTable(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSession_Save(t *testing.T) {
+	s := testNoteInit(t)
+	convey.Convey("SAVE 测试", t, func() {
+		note := &Note{Title: "a", Content: "x"}
+		_, err := s.Save(note)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(note.ID, convey.ShouldEqual, 1)
+
+		note.Content = ""
+		affected, err := s.Save(note)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(affected, convey.ShouldEqual, 1)
+
+		// 主键不为零值但记录不存在时插入
+		_, err = s.Save(&Note{ID: 5, Title: "b"})
+		convey.So(err, convey.ShouldBeNil)
+
+		var notes []Note
+		convey.So(s.OrderBy("ID").Find(&notes), convey.ShouldBeNil)
+		convey.So(notes, convey.ShouldResemble, []Note{{ID: 1, Title: "a"}, {ID: 5, Title: "b"}})
+	})
+}
+
+func TestSession_Updates(t *testing.T) {
+	s := testNoteInit(t)
+	convey.Convey("UPDATES 测试", t, func() {
+		_, _ = s.Insert(&Note{Title: "a", Content: "x"})
+		affected, err := s.Updates(&Note{ID: 1, Content: "y"})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(affected, convey.ShouldEqual, 1)
+
+		note := &Note{}
+		convey.So(s.First(note), convey.ShouldBeNil)
+		convey.So(*note, convey.ShouldResemble, Note{ID: 1, Title: "a", Content: "y"})
+
+		_, err = s.Updates(&Note{Content: "z"})
+		convey.So(err, convey.ShouldEqual, ErrMissingPrimaryKey)
+	})
+}
+
+func TestSession_DeleteModel(t *testing.T) {
+	s := testNoteInit(t)
+	convey.Convey("DELETE MODEL 测试", t, func() {
+		_, _ = s.Insert(&Note{Title: "a"}, &Note{Title: "b"})
+		affected, err := s.DeleteModel(&Note{ID: 1})
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(affected, convey.ShouldEqual, 1)
+
+		_, err = s.DeleteModel(&Note{Title: "b"})
+		convey.So(err, convey.ShouldEqual, ErrMissingPrimaryKey)
+		count, _ := s.Count()
+		convey.So(count, convey.ShouldEqual, 1)
+	})
+}
+
+func TestSession_GlobalGuard(t *testing.T) {
+	s := testNoteInit(t)
+	convey.Convey("缺少 WHERE 保护测试", t, func() {
+		_, _ = s.Insert(&Note{Title: "a"}, &Note{Title: "b"})
+		_, err := s.Update("Title", "c")
+		convey.So(err, convey.ShouldEqual, ErrMissingWhere)
+		_, err = s.OrderBy("ID").Delete()
+		convey.So(err, convey.ShouldEqual, ErrMissingWhere)
+		count, _ := s.Count()
+		convey.So(count, convey.ShouldEqual, 2)
+
+		affected, err := s.AllowGlobalUpdate().Delete()
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(affected, convey.ShouldEqual, 2)
+
+		// 只对下一条语句有效
+		_, err = s.Delete()
+		convey.So(err, convey.ShouldEqual, ErrMissingWhere)
+	})
+}
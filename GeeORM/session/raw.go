@@ -12,13 +12,16 @@ import (
 )
 
 type Session struct {
-	db       *sql.DB         // sql.Open() 方法连接数据库成功后返回的指针;
-	sql      strings.Builder // 用来拼接 SQL 语句;
-	sqlVars  []interface{}   // SQL 语句中的占位符的对应值;
-	dialect  dialect.Dialect // SQL 数据库适配
-	refTable *schema.Schema  // 表与对象的映射
-	clause   clause.Clause   // 用于构造 SQL 语句
-	tx       *sql.Tx         // 用于支持 SQL 事务
+	db       *sql.DB               // sql.Open() 方法连接数据库成功后返回的指针;
+	sql      strings.Builder       // 用来拼接 SQL 语句;
+	sqlVars  []interface{}         // SQL 语句中的占位符的对应值;
+	dialect  dialect.Dialect       // SQL 数据库适配
+	refTable *schema.Schema        // 表与对象的映射
+	naming   schema.NamingStrategy // 表名和列名的命名策略
+	clause   clause.Clause         // 用于构造 SQL 语句
+	tx       *sql.Tx               // 用于支持 SQL 事务
+
+	allowGlobal bool // 允许下一条 UPDATE 或 DELETE 不带 WHERE
 }
 
 type CommonDB interface {
@@ -44,12 +47,21 @@ func New(db *sql.DB, dialect dialect.Dialect) *Session {
 	}
 }
 
+// SetNamingStrategy 设置解析表结构时使用的命名策略, 为 nil 时使用 Go 中的名字
+func (s *Session) SetNamingStrategy(naming schema.NamingStrategy) *Session {
+	s.naming = naming
+	s.refTable = nil
+	return s
+}
+
 func (s *Session) Clear() {
 	s.sql.Reset()   // 清空 SQL 语句
 	s.sqlVars = nil // 清空 SQL 变量
 	s.clause = clause.Clause{}
+	s.allowGlobal = false
 }
 
+// Raw 追加一段 SQL, 语句原样执行, 占位符需要使用方言自己的形式 (如 PostgreSQL 的 $1)
 func (s *Session) Raw(sql string, values ...interface{}) *Session {
 	s.sql.WriteString(sql)
 	s.sql.WriteString(" ")
@@ -59,8 +71,9 @@ func (s *Session) Raw(sql string, values ...interface{}) *Session {
 
 func (s *Session) Exec() (sql.Result, error) {
 	defer s.Clear()
-	log.Info(s.sql.String(), s.sqlVars)
-	result, err := s.DB().Exec(s.sql.String(), s.sqlVars...)
+	query := s.sql.String()
+	log.Info(query, s.sqlVars)
+	result, err := s.DB().Exec(query, s.sqlVars...)
 	if err != nil {
 		log.Error(err)
 	}
@@ -69,14 +82,16 @@ func (s *Session) Exec() (sql.Result, error) {
 
 func (s *Session) QueryRow() *sql.Row {
 	defer s.Clear()
-	log.Info(s.sql.String(), s.sqlVars)
-	return s.DB().QueryRow(s.sql.String(), s.sqlVars...)
+	query := s.sql.String()
+	log.Info(query, s.sqlVars)
+	return s.DB().QueryRow(query, s.sqlVars...)
 }
 
 func (s *Session) QueryRows() (*sql.Rows, error) {
 	defer s.Clear()
-	log.Info(s.sql.String(), s.sqlVars)
-	rows, err := s.DB().Query(s.sql.String(), s.sqlVars...)
+	query := s.sql.String()
+	log.Info(query, s.sqlVars)
+	rows, err := s.DB().Query(query, s.sqlVars...)
 	if err != nil {
 		log.Error(err)
 	}
@@ -2,33 +2,203 @@ package session
 
 import (
 	"GeeORM/clause"
+	"GeeORM/dialect"
+	"GeeORM/log"
+	"GeeORM/schema"
 	"errors"
+	"fmt"
 	"reflect"
 )
 
+// Insert 插入一条或多条记录, 返回插入的行数
+// 自增字段为零值的记录在插入时省略该列, 数据库生成的 ID 会写回 values 中的指针
 func (s *Session) Insert(values ...interface{}) (int64, error) {
+	if len(values) == 0 {
+		return 0, nil
+	}
 	// values: 要插入的 object
-	recordValues := make([]interface{}, 0)
 	for _, value := range values {
 		s.CallMethod(BeforeInsert, value)
-		table := s.Model(value).RefTable()
-		// 其实只需要设置一次, 这里是为了方便
-		s.clause.Set(clause.INSERT, table.Name, table.FieldNames)
-		// object -> 展开成 record
-		recordValues = append(recordValues, table.RecordValues(value))
 	}
+	table := s.Model(values[0]).RefTable()
 
-	s.clause.Set(clause.VALUES, recordValues...)
-	sql, vars := s.clause.Build(clause.INSERT, clause.VALUES)
+	// 自增字段为零值的记录需要数据库生成 ID, 与指定了 ID 的记录分成两条语句插入
+	var generated, given []interface{}
+	for _, value := range values {
+		if auto := table.AutoIncrementField; auto != nil && isZero(auto, value) {
+			generated = append(generated, value)
+		} else {
+			given = append(given, value)
+		}
+	}
+
+	// 分成两条语句插入时, 不在事务中则开启一个事务, 避免只插入了其中一部分
+	if len(given) > 0 && len(generated) > 0 && s.tx == nil {
+		if err := s.Begin(); err != nil {
+			return 0, err
+		}
+		defer func() { s.tx = nil }()
+		affected, err := s.insertBoth(table, given, generated)
+		if err != nil {
+			_ = s.Rollback()
+			return 0, err
+		}
+		if err := s.Commit(); err != nil {
+			return 0, err
+		}
+		s.CallMethod(AfterInsert, nil)
+		return affected, nil
+	}
 
+	affected, err := s.insertBoth(table, given, generated)
+	if err != nil {
+		return affected, err
+	}
+	s.CallMethod(AfterInsert, nil)
+	return affected, nil
+}
+
+// insertBoth 依次插入指定了 ID 的记录和需要数据库生成 ID 的记录
+func (s *Session) insertBoth(table *schema.Schema, given, generated []interface{}) (int64, error) {
+	var affected int64
+	if len(given) > 0 {
+		n, err := s.insert(table, given, table.Fields)
+		if err != nil {
+			return affected, err
+		}
+		affected += n
+	}
+	if len(generated) > 0 {
+		n, err := s.insertGenerated(table, generated)
+		if err != nil {
+			return affected, err
+		}
+		affected += n
+	}
+	return affected, nil
+}
+
+func (s *Session) insert(table *schema.Schema, values []interface{}, fields []*schema.Field) (int64, error) {
+	s.setInsert(table, values, fields)
+	sql, vars := s.clause.Build(s.dialect, clause.INSERT, clause.VALUES)
 	result, err := s.Raw(sql, vars...).Exec()
 	if err != nil {
 		return 0, err
 	}
-	s.CallMethod(AfterInsert, nil)
 	return result.RowsAffected()
 }
 
+// insertGenerated 插入时省略自增列, 并把数据库生成的 ID 写回 values
+func (s *Session) insertGenerated(table *schema.Schema, values []interface{}) (int64, error) {
+	auto := table.AutoIncrementField
+	var fields []*schema.Field
+	for _, field := range table.Fields {
+		if field != auto {
+			fields = append(fields, field)
+		}
+	}
+
+	mode := s.dialect.InsertIDMode()
+	if mode == dialect.ReturningID {
+		s.setInsert(table, values, fields)
+		s.clause.Set(clause.RETURNING, []string{auto.Column})
+		sql, vars := s.clause.Build(s.dialect, clause.INSERT, clause.VALUES, clause.RETURNING)
+		rows, err := s.Raw(sql, vars...).QueryRows()
+		if err != nil {
+			return 0, err
+		}
+		// RETURNING 按 VALUES 的顺序返回每一行的 ID
+		var affected int64
+		for ; rows.Next(); affected++ {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				_ = rows.Close()
+				return affected, err
+			}
+			if affected < int64(len(values)) {
+				setID(auto, values[affected], id)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			_ = rows.Close()
+			return affected, err
+		}
+		if err := rows.Close(); err != nil {
+			return affected, err
+		}
+		return affected, checkInserted(affected, len(values))
+	}
+
+	s.setInsert(table, values, fields)
+	sql, vars := s.clause.Build(s.dialect, clause.INSERT, clause.VALUES)
+	result, err := s.Raw(sql, vars...).Exec()
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	// 生成的 ID 按插入的行数推算, 行数不一致时无法写回
+	if err := checkInserted(affected, len(values)); err != nil {
+		return affected, err
+	}
+	if mode == dialect.LastIDOfBatch {
+		id -= int64(len(values) - 1)
+	}
+	for i, value := range values {
+		setID(auto, value, id+int64(i))
+	}
+	return affected, nil
+}
+
+// checkInserted 检查插入的行数与记录数是否一致
+func checkInserted(affected int64, n int) error {
+	if affected != int64(n) {
+		return fmt.Errorf("geeorm: inserted %d rows, expected %d", affected, n)
+	}
+	return nil
+}
+
+// setInsert 设置 INSERT 和 VALUES 子句, 只包含 fields 中的列
+func (s *Session) setInsert(table *schema.Schema, values []interface{}, fields []*schema.Field) {
+	columns := make([]string, 0, len(fields))
+	for _, field := range fields {
+		columns = append(columns, field.Column)
+	}
+	s.clause.Set(clause.INSERT, table.Name, columns)
+	// object -> 展开成 record
+	recordValues := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		recordValues = append(recordValues, table.RecordValuesOf(value, fields))
+	}
+	s.clause.Set(clause.VALUES, recordValues...)
+}
+
+func isZero(field *schema.Field, value interface{}) bool {
+	v := field.ValueOf(reflect.Indirect(reflect.ValueOf(value)))
+	return !v.IsValid() || v.IsZero()
+}
+
+// setID 把 id 写入 value 的自增字段, value 不是指针时无法写回, 直接忽略
+func setID(field *schema.Field, value interface{}, id int64) {
+	dest := reflect.ValueOf(value)
+	if dest.Kind() != reflect.Ptr || dest.IsNil() {
+		return
+	}
+	v := reflect.ValueOf(field.AddrOf(dest.Elem())).Elem()
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(id)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(uint64(id))
+	}
+}
+
 func (s *Session) Find(values interface{}) error {
 	s.CallMethod(BeforeQuery, nil)
 	destSlice := reflect.Indirect(reflect.ValueOf(values))
@@ -40,20 +210,31 @@ func (s *Session) Find(values interface{}) error {
 
 	// 根据表结构构造对应的 SELECT 语句, 查找所有符合条件的 record -> rows
 	s.clause.Set(clause.SELECT, table.Name, table.FieldNames)
-	sql, vars := s.clause.Build(clause.SELECT, clause.WHERE, clause.ORDERBY, clause.LIMIT)
+	sql, vars := s.clause.Build(s.dialect, clause.SELECT, clause.WHERE, clause.ORDERBY, clause.LIMIT)
 	rows, err := s.Raw(sql, vars...).QueryRows()
 	if err != nil {
 		return err
 	}
 
+	columns, err := rows.Columns()
+	if err != nil {
+		_ = rows.Close()
+		return err
+	}
+
 	// 遍历每一行, 利用 reflect 创建 object 实例 -> dest
 	for rows.Next() {
 		dest := reflect.New(destType).Elem()
 		var values []interface{}
 
-		// 将 dest 的所有字段平铺, 构造切片 values
-		for _, name := range table.FieldNames {
-			values = append(values, dest.FieldByName(name).Addr().Interface())
+		// 按结果集的列名找到 dest 中对应的字段, 构造切片 values
+		for _, column := range columns {
+			field := table.GetField(column)
+			if field == nil {
+				values = append(values, new(interface{}))
+				continue
+			}
+			values = append(values, field.AddrOf(dest))
 		}
 
 		// 将每一列的值依次赋值给 values 的每一个字段
@@ -67,6 +248,19 @@ func (s *Session) Find(values interface{}) error {
 	return rows.Close()
 }
 
+var (
+	// ErrMissingWhere 表示 UPDATE 或 DELETE 语句没有 WHERE 子句, 需要调用 AllowGlobalUpdate 显式允许
+	ErrMissingWhere = errors.New("geeorm: UPDATE or DELETE without WHERE, call AllowGlobalUpdate to allow it")
+	// ErrMissingPrimaryKey 表示模型没有主键字段, 或者主键为零值
+	ErrMissingPrimaryKey = errors.New("geeorm: missing primary key")
+)
+
+// AllowGlobalUpdate 允许下一条 UPDATE 或 DELETE 语句不带 WHERE 子句, 作用于整张表
+func (s *Session) AllowGlobalUpdate() *Session {
+	s.allowGlobal = true
+	return s
+}
+
 func (s *Session) Update(kv ...interface{}) (int64, error) {
 	s.CallMethod(BeforeUpdate, nil)
 	m, ok := kv[0].(map[string]interface{})
@@ -76,31 +270,63 @@ func (s *Session) Update(kv ...interface{}) (int64, error) {
 			m[kv[i].(string)] = kv[i+1]
 		}
 	}
+	affected, err := s.update(m)
+	if err != nil {
+		return 0, err
+	}
+	s.CallMethod(AfterUpdate, nil)
+	return affected, nil
+}
+
+func (s *Session) update(m map[string]interface{}) (int64, error) {
+	if err := s.checkWhere(); err != nil {
+		return 0, err
+	}
 	s.clause.Set(clause.UPDATE, s.RefTable().Name, m)
-	sql, vars := s.clause.Build(clause.UPDATE, clause.WHERE)
+	sql, vars := s.clause.Build(s.dialect, clause.UPDATE, clause.WHERE)
 	result, err := s.Raw(sql, vars...).Exec()
 	if err != nil {
 		return 0, err
 	}
-	s.CallMethod(AfterUpdate, nil)
 	return result.RowsAffected()
 }
 
 func (s *Session) Delete() (int64, error) {
 	s.CallMethod(BeforeDelete, nil)
+	affected, err := s.delete()
+	if err != nil {
+		return 0, err
+	}
+	s.CallMethod(AfterDelete, nil)
+	return affected, nil
+}
+
+func (s *Session) delete() (int64, error) {
+	if err := s.checkWhere(); err != nil {
+		return 0, err
+	}
 	s.clause.Set(clause.DELETE, s.RefTable().Name)
-	sql, vars := s.clause.Build(clause.DELETE, clause.WHERE)
+	sql, vars := s.clause.Build(s.dialect, clause.DELETE, clause.WHERE)
 	result, err := s.Raw(sql, vars...).Exec()
 	if err != nil {
 		return 0, err
 	}
-	s.CallMethod(AfterDelete, nil)
 	return result.RowsAffected()
 }
 
+// checkWhere 拒绝没有 WHERE 子句且没有显式允许的 UPDATE 和 DELETE, 并清空已设置的子句
+func (s *Session) checkWhere() error {
+	if s.clause.Has(clause.WHERE) || s.allowGlobal {
+		return nil
+	}
+	s.Clear()
+	log.Error(ErrMissingWhere)
+	return ErrMissingWhere
+}
+
 func (s *Session) Count() (int64, error) {
 	s.clause.Set(clause.COUNT, s.RefTable().Name)
-	sql, vars := s.clause.Build(clause.DELETE, clause.WHERE)
+	sql, vars := s.clause.Build(s.dialect, clause.COUNT, clause.WHERE)
 	row := s.Raw(sql, vars...).QueryRow()
 	var tmp int64
 	if err := row.Scan(&tmp); err != nil {
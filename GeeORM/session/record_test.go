@@ -85,3 +85,32 @@ func TestSession_DeleteAndCount(t *testing.T) {
 		convey.So(count, convey.ShouldEqual, 1)
 	})
 }
+
+type Purchase struct {
+	ID   int64 `geeorm:"primaryKey;autoIncrement"`
+	Item string
+}
+
+func TestSession_InsertAutoIncrement(t *testing.T) {
+	s := NewSession().Model(&Purchase{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	convey.Convey("自增主键测试", t, func() {
+		first := &Purchase{Item: "apple"}
+		_, err := s.Insert(first)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(first.ID, convey.ShouldEqual, 1)
+
+		batch := []*Purchase{{Item: "banana"}, {ID: 10, Item: "cherry"}, {Item: "durian"}}
+		affected, err := s.Insert(batch[0], batch[1], batch[2])
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(affected, convey.ShouldEqual, 3)
+
+		var purchases []Purchase
+		convey.So(s.OrderBy("Item").Find(&purchases), convey.ShouldBeNil)
+		convey.So(purchases, convey.ShouldResemble, []Purchase{
+			{ID: 1, Item: "apple"}, *batch[0], {ID: 10, Item: "cherry"}, *batch[2]})
+		convey.So(batch[0].ID, convey.ShouldBeGreaterThan, 10)
+		convey.So(batch[2].ID, convey.ShouldEqual, batch[0].ID+1)
+	})
+}
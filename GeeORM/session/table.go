@@ -13,7 +13,7 @@ import (
 func (s *Session) Model(value interface{}) *Session {
 	// 用于给 refTable 赋值
 	if s.refTable == nil || reflect.TypeOf(value) != reflect.TypeOf(s.refTable.Model) {
-		s.refTable = schema.Parse(value, s.dialect)
+		s.refTable = schema.Parse(value, s.dialect, s.naming)
 	}
 	return s
 }
@@ -29,16 +29,16 @@ func (s *Session) CreateTable() error {
 	table := s.RefTable()
 	var columns []string
 	for _, field := range table.Fields {
-		columns = append(columns, fmt.Sprintf("%s %s %s", field.Name, field.Name, field.Tag))
+		columns = append(columns, fmt.Sprintf("%s %s %s", field.Column, field.Type, field.Tag))
 	}
 
 	desc := strings.Join(columns, ",")
-	_, err := s.Raw(fmt.Sprintf("CREATE TABLE %s (%s);", table.Name, desc)).Exec()
+	_, err := s.Raw(fmt.Sprintf("CREATE TABLE %s (%s);", s.dialect.Quote(table.Name), desc)).Exec()
 	return err
 }
 
 func (s *Session) DropTable() error {
-	_, err := s.Raw(fmt.Sprintf("DROP TABLE IF EXISTS %s", s.RefTable().Name)).Exec()
+	_, err := s.Raw(fmt.Sprintf("DROP TABLE IF EXISTS %s", s.dialect.Quote(s.RefTable().Name))).Exec()
 	return err
 }
 
@@ -1,6 +1,7 @@
 package session
 
 import (
+	"database/sql"
 	"github.com/smartystreets/goconvey/convey"
 	"testing"
 )
@@ -28,3 +29,100 @@ func TestSession_CreateTable(t *testing.T) {
 	})
 
 }
+
+type Member struct {
+	ID     int    `geeorm:"column:id;primaryKey"`
+	Name   string `geeorm:"column:member_name;notNull"`
+	Secret string `geeorm:"-"`
+}
+
+func (m Member) TableName() string {
+	return "members"
+}
+
+func TestSession_TagMapping(t *testing.T) {
+	s := NewSession().Model(&Member{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	convey.Convey("标签映射测试", t, func() {
+		_, err := s.Insert(&Member{ID: 1, Name: "Tom", Secret: "x"})
+		convey.So(err, convey.ShouldBeNil)
+
+		var members []Member
+		err = s.Where("member_name = ?", "Tom").Find(&members)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(members, convey.ShouldResemble, []Member{{ID: 1, Name: "Tom"}})
+		convey.So(s.HasTable(), convey.ShouldBeTrue)
+	})
+}
+
+type Base struct {
+	ID int `geeorm:"primaryKey"`
+}
+
+type Contact struct {
+	Phone string
+}
+
+type Customer struct {
+	Base
+	*Contact `geeorm:"embeddedPrefix:contact_"`
+	Name     string
+}
+
+func TestSession_Embedded(t *testing.T) {
+	s := NewSession().Model(&Customer{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	convey.Convey("嵌入字段测试", t, func() {
+		_, err := s.Insert(&Customer{Base: Base{ID: 1}, Contact: &Contact{Phone: "10086"}, Name: "Tom"})
+		convey.So(err, convey.ShouldBeNil)
+
+		var customers []Customer
+		err = s.Where("contact_Phone = ?", "10086").Find(&customers)
+		convey.So(err, convey.ShouldBeNil)
+		convey.So(customers, convey.ShouldHaveLength, 1)
+		convey.So(customers[0].ID, convey.ShouldEqual, 1)
+		convey.So(customers[0].Contact.Phone, convey.ShouldEqual, "10086")
+		convey.So(customers[0].Name, convey.ShouldEqual, "Tom")
+	})
+}
+
+type Address struct {
+	City string
+}
+
+type Profile struct {
+	ID       int `geeorm:"primaryKey"`
+	Nickname *string
+	Age      *int
+	Email    sql.NullString
+	Tags     []string          `geeorm:"serializer:json"`
+	Extra    map[string]string `geeorm:"serializer:json"`
+	Address  *Address          `geeorm:"serializer:json"`
+}
+
+func TestSession_Nullable(t *testing.T) {
+	s := NewSession().Model(&Profile{})
+	_ = s.DropTable()
+	_ = s.CreateTable()
+	convey.Convey("可空字段与 JSON 序列化测试", t, func() {
+		nickname, age := "tom", 18
+		_, err := s.Insert(
+			&Profile{ID: 1, Nickname: &nickname, Age: &age, Email: sql.NullString{String: "tom@example.com", Valid: true},
+				Tags: []string{"a", "b"}, Extra: map[string]string{"k": "v"}, Address: &Address{City: "Beijing"}},
+			&Profile{ID: 2})
+		convey.So(err, convey.ShouldBeNil)
+
+		var profiles []Profile
+		convey.So(s.OrderBy("ID").Find(&profiles), convey.ShouldBeNil)
+		convey.So(profiles, convey.ShouldHaveLength, 2)
+		convey.So(*profiles[0].Nickname, convey.ShouldEqual, "tom")
+		convey.So(*profiles[0].Age, convey.ShouldEqual, 18)
+		convey.So(profiles[0].Email.String, convey.ShouldEqual, "tom@example.com")
+		convey.So(profiles[0].Tags, convey.ShouldResemble, []string{"a", "b"})
+		convey.So(profiles[0].Extra, convey.ShouldResemble, map[string]string{"k": "v"})
+		convey.So(profiles[0].Address.City, convey.ShouldEqual, "Beijing")
+		convey.So(profiles[1], convey.ShouldResemble, Profile{ID: 2})
+	})
+}